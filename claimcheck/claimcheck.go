@@ -0,0 +1,133 @@
+// Package claimcheck offloads large message payloads to a BlobStore.
+//
+// Payloads larger than a threshold are uploaded to the BlobStore, and published messages carry the object name
+// as data with the "claim-check" metadata. Consumers fetch the payloads with Resolve or SubscribeInterceptor.
+// Objects are not deleted after consumption because a message can be delivered to multiple subscriptions,
+// so expire them with lifecycle rules of the storage.
+// To compress or encrypt payloads, do it before offloading.
+package claimcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/izumin5210/pubee"
+)
+
+const (
+	// DefaultThreshold is the default size offloading payloads, which leaves room for
+	// the 10MB message size limit of Cloud Pub/Sub.
+	DefaultThreshold = 8 << 20
+
+	// MarkerKey is the metadata key marking a message as a claim-check.
+	MarkerKey = "claim-check"
+	// SizeKey is the metadata key of the offloaded payload size.
+	SizeKey = "claim-check-size"
+)
+
+// WithClaimCheck returns a pubee.Option that offloads large message payloads to the BlobStore.
+func WithClaimCheck(s BlobStore, opts ...Option) pubee.Option {
+	return pubee.WithPublishInterceptors(PublishInterceptor(s, opts...))
+}
+
+// PublishInterceptor returns a pubee.PublishInterceptor that offloads large message payloads to the BlobStore.
+// Payloads are uploaded before publishing, and upload failures are returned as publish errors.
+func PublishInterceptor(s BlobStore, opts ...Option) pubee.PublishInterceptor {
+	cfg := &Config{Threshold: DefaultThreshold, NameFunc: randomName}
+	cfg.apply(opts)
+
+	return func(ctx context.Context, msg *pubee.Message, h pubee.PublishHandler) <-chan error {
+		if len(msg.Data) <= cfg.Threshold {
+			return h(ctx, msg)
+		}
+
+		name, err := offload(ctx, s, cfg, msg)
+		if err != nil {
+			errCh := make(chan error, 1)
+			errCh <- err
+			close(errCh)
+			return errCh
+		}
+
+		md := make(map[string]string, len(msg.Metadata)+2)
+		for k, v := range msg.Metadata {
+			md[k] = v
+		}
+		md[MarkerKey] = "true"
+		md[SizeKey] = strconv.Itoa(len(msg.Data))
+
+		msg.Data = []byte(name)
+		msg.Metadata = md
+
+		return h(ctx, msg)
+	}
+}
+
+func offload(ctx context.Context, s BlobStore, cfg *Config, msg *pubee.Message) (string, error) {
+	name, err := cfg.NameFunc(msg)
+	if err != nil {
+		return "", fmt.Errorf("claimcheck: failed to name an object: %w", err)
+	}
+	if err := s.Put(ctx, name, msg.Data); err != nil {
+		return "", fmt.Errorf("claimcheck: failed to upload %q: %w", name, err)
+	}
+	return name, nil
+}
+
+// IsClaimCheck reports whether the metadata marks a message as a claim-check.
+func IsClaimCheck(metadata map[string]string) bool {
+	_, ok := metadata[MarkerKey]
+	return ok
+}
+
+// Resolve fetches the payload referenced by data from the BlobStore.
+// Data are returned as is when the metadata has no claim-check marker.
+func Resolve(ctx context.Context, s BlobStore, data []byte, metadata map[string]string) ([]byte, error) {
+	if !IsClaimCheck(metadata) {
+		return data, nil
+	}
+
+	payload, err := s.Get(ctx, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("claimcheck: failed to fetch %q: %w", data, err)
+	}
+
+	return payload, nil
+}
+
+// ResolveMessage replaces the message data with the referenced payload, and removes the claim-check metadata.
+func ResolveMessage(ctx context.Context, s BlobStore, m *pubee.ReceivedMessage) error {
+	if !IsClaimCheck(m.Metadata) {
+		return nil
+	}
+
+	data, err := Resolve(ctx, s, m.Data, m.Metadata)
+	if err != nil {
+		return err
+	}
+
+	md := make(map[string]string, len(m.Metadata))
+	for k, v := range m.Metadata {
+		switch k {
+		case MarkerKey, SizeKey:
+		default:
+			md[k] = v
+		}
+	}
+
+	m.Data = data
+	m.Metadata = md
+
+	return nil
+}
+
+// SubscribeInterceptor returns a pubee.SubscribeInterceptor that resolves received messages before handlers.
+func SubscribeInterceptor(s BlobStore) pubee.SubscribeInterceptor {
+	return func(ctx context.Context, m *pubee.ReceivedMessage, h pubee.Handler) error {
+		if err := ResolveMessage(ctx, s, m); err != nil {
+			return err
+		}
+		return h(ctx, m)
+	}
+}
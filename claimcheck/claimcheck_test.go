@@ -0,0 +1,153 @@
+package claimcheck_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/claimcheck"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+type Document struct {
+	Body string `json:"body"`
+}
+
+func newFileStore(t *testing.T) (*claimcheck.FileStore, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "pubee-claimcheck")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	return claimcheck.NewFileStore(dir), func() { os.RemoveAll(dir) }
+}
+
+func TestWithClaimCheck(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newFileStore(t)
+	defer cleanup()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, claimcheck.WithClaimCheck(store, claimcheck.WithThreshold(1024)), pubee.WithMetadata("foo", "bar"))
+
+	doc := &Document{Body: strings.Repeat("pubee ", 1000)}
+	publisher.Publish(ctx, doc)
+	publisher.Publish(ctx, &Document{Body: "small"})
+	publisher.Close(ctx)
+
+	msgs := driver.Messages()
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Published %d messages, want %d", got, want)
+	}
+	if !claimcheck.IsClaimCheck(msgs[0].Metadata) {
+		t.Error("Large message should be a claim-check")
+	}
+	if got, limit := len(msgs[0].Data), 1024; got > limit {
+		t.Errorf("Published message has %d bytes, want at most %d", got, limit)
+	}
+	if claimcheck.IsClaimCheck(msgs[1].Metadata) {
+		t.Error("Small message should not be a claim-check")
+	}
+
+	m := &pubee.ReceivedMessage{Data: msgs[0].Data, Metadata: msgs[0].Metadata}
+	if err := claimcheck.ResolveMessage(ctx, store, m); err != nil {
+		t.Fatalf("ResolveMessage() returned %v", err)
+	}
+	var got Document
+	if err := m.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal() returned %v", err)
+	}
+	if got.Body != doc.Body {
+		t.Error("Resolved message differs from the published one")
+	}
+	if claimcheck.IsClaimCheck(m.Metadata) {
+		t.Error("Resolved message should not be a claim-check")
+	}
+	if got, want := m.Metadata["foo"], "bar"; got != want {
+		t.Errorf("Resolved message has metadata %q, want %q", got, want)
+	}
+}
+
+func TestWithClaimCheck_WhenUploadFailed(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	nameErr := errors.New("unfortunate error")
+	store, cleanup := newFileStore(t)
+	defer cleanup()
+	publisher := pubee.New(driver, claimcheck.WithClaimCheck(store,
+		claimcheck.WithThreshold(1),
+		claimcheck.WithNameFunc(func(*pubee.Message) (string, error) { return "", nameErr }),
+	))
+	defer publisher.Close(ctx)
+
+	if _, err := publisher.Publish(ctx, "foo").Get(ctx); !errors.Is(err, nameErr) {
+		t.Errorf("Publish() returned %v, want %v", err, nameErr)
+	}
+	if got, want := len(driver.Messages()), 0; got != want {
+		t.Errorf("Published %d messages, want %d", got, want)
+	}
+}
+
+func TestSubscribeInterceptor(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup := newFileStore(t)
+	defer cleanup()
+	if err := store.Put(ctx, "foo/bar", []byte("payload")); err != nil {
+		t.Fatalf("Put() returned %v", err)
+	}
+
+	var got []string
+	h := func(_ context.Context, m *pubee.ReceivedMessage) error {
+		got = append(got, string(m.Data))
+		return nil
+	}
+	interceptor := claimcheck.SubscribeInterceptor(store)
+	md := map[string]string{claimcheck.MarkerKey: "true"}
+
+	if err := interceptor(ctx, &pubee.ReceivedMessage{Data: []byte("foo/bar"), Metadata: md}, h); err != nil {
+		t.Errorf("interceptor returned %v", err)
+	}
+	if err := interceptor(ctx, &pubee.ReceivedMessage{Data: []byte("plain")}, h); err != nil {
+		t.Errorf("interceptor returned %v for a plain message", err)
+	}
+	if err := interceptor(ctx, &pubee.ReceivedMessage{Data: []byte("missing"), Metadata: md}, h); !errors.Is(err, claimcheck.ErrNotFound) {
+		t.Errorf("interceptor returned %v, want %v", err, claimcheck.ErrNotFound)
+	}
+	if got, want := strings.Join(got, ","), "payload,plain"; got != want {
+		t.Errorf("Handled messages are %q, want %q", got, want)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "pubee-claimcheck")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	store := claimcheck.NewFileStore(dir + "/store")
+
+	if err := store.Put(ctx, "../escaped", []byte("foo")); err != nil {
+		t.Fatalf("Put() returned %v", err)
+	}
+	if _, err := os.Stat(dir + "/escaped"); !os.IsNotExist(err) {
+		t.Error("Put() should not write objects outside the directory")
+	}
+	if data, err := store.Get(ctx, "escaped"); err != nil || string(data) != "foo" {
+		t.Errorf("Get() returned %q, %v, want %q, nil", data, err, "foo")
+	}
+
+	if err := store.Delete(ctx, "escaped"); err != nil {
+		t.Errorf("Delete() returned %v", err)
+	}
+	if _, err := store.Get(ctx, "escaped"); err != claimcheck.ErrNotFound {
+		t.Errorf("Get() returned %v after Delete(), want %v", err, claimcheck.ErrNotFound)
+	}
+	if err := store.Delete(ctx, "escaped"); err != nil {
+		t.Errorf("Delete() returned %v for a missing object", err)
+	}
+}
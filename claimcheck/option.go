@@ -0,0 +1,45 @@
+package claimcheck
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Config represents claim-check configuration.
+type Config struct {
+	Threshold int
+	NameFunc  func(*pubee.Message) (string, error)
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is claim-check Option
+type Option func(*Config)
+
+// WithThreshold returns an Option that offloads messages larger than n bytes. Defaults to DefaultThreshold.
+func WithThreshold(n int) Option {
+	return func(c *Config) {
+		c.Threshold = n
+	}
+}
+
+// WithNameFunc returns an Option that names objects with f. Objects are named randomly by default.
+func WithNameFunc(f func(*pubee.Message) (string, error)) Option {
+	return func(c *Config) {
+		c.NameFunc = f
+	}
+}
+
+func randomName(msg *pubee.Message) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
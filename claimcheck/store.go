@@ -0,0 +1,79 @@
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("claimcheck: blob not found")
+
+// BlobStore stores payloads as named objects, like a Cloud Storage bucket.
+type BlobStore interface {
+	// Put writes data to the object.
+	Put(ctx context.Context, name string, data []byte) error
+	// Get reads data from the object. It returns ErrNotFound when the object does not exist.
+	Get(ctx context.Context, name string) ([]byte, error)
+	// Delete deletes the object.
+	Delete(ctx context.Context, name string) error
+}
+
+// FileStore is a BlobStore storing objects into a local directory.
+type FileStore struct {
+	dir string
+}
+
+var _ BlobStore = (*FileStore)(nil)
+
+// NewFileStore creates a FileStore storing objects under dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) Put(ctx context.Context, name string, data []byte) error {
+	p := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+func (s *FileStore) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *FileStore) Delete(ctx context.Context, name string) error {
+	err := os.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path returns the file path of the object, and keeps it under the directory.
+func (s *FileStore) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+name)))
+}
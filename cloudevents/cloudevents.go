@@ -0,0 +1,165 @@
+// Package cloudevents wraps published messages as CloudEvents 1.0, and decodes them on the consumer side.
+//
+// In binary mode, event attributes are stored into message metadata with the "ce-" prefix,
+// and "datacontenttype" is the "content-type" metadata set by codecs.
+// In structured mode, message data are replaced with a JSON envelope of the "application/cloudevents+json" content type.
+package cloudevents
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/codec"
+)
+
+const (
+	// SpecVersion is the version of the CloudEvents specification.
+	SpecVersion = "1.0"
+	// MetadataPrefix is the prefix of metadata keys for event attributes in binary mode.
+	MetadataPrefix = "ce-"
+	// ContentType is the content type of structured mode messages.
+	ContentType = "application/cloudevents+json"
+)
+
+// WithEnvelope returns a pubee.Option that wraps published messages as CloudEvents.
+func WithEnvelope(opts ...Option) pubee.Option {
+	return pubee.WithPublishInterceptors(PublishInterceptor(opts...))
+}
+
+// PublishInterceptor returns a pubee.PublishInterceptor that wraps published messages as CloudEvents.
+// Attributes already in the metadata, such as ones set by WithEventType, take precedence over generated ones.
+func PublishInterceptor(opts ...Option) pubee.PublishInterceptor {
+	cfg := &Config{
+		IDFunc:   newUUID,
+		TypeFunc: TypeOf,
+	}
+	cfg.apply(opts)
+
+	return func(ctx context.Context, msg *pubee.Message, h pubee.PublishHandler) <-chan error {
+		meta := make(map[string]string, len(msg.Metadata)+5)
+		for k, v := range msg.Metadata {
+			meta[k] = v
+		}
+
+		setDefault(meta, "specversion", SpecVersion)
+		setDefault(meta, "id", cfg.IDFunc())
+		setDefault(meta, "source", firstNonEmpty(cfg.Source, msg.Topic, "pubee"))
+		setDefault(meta, "type", cfg.TypeFunc(msg.Original))
+		setDefault(meta, "time", time.Now().UTC().Format(time.RFC3339Nano))
+
+		msg.Metadata = meta
+
+		if cfg.Mode == Structured {
+			data, err := encodeStructured(msg)
+			if err != nil {
+				errCh := make(chan error, 1)
+				errCh <- err
+				close(errCh)
+				return errCh
+			}
+			msg.Data = data
+		}
+
+		return h(ctx, msg)
+	}
+}
+
+// TypeOf returns the full name of a protobuf message, or the package path and name of the Go type of v.
+func TypeOf(v interface{}) string {
+	if m, ok := v.(proto.Message); ok {
+		if name := proto.MessageName(m); name != "" {
+			return name
+		}
+	}
+
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return "nil"
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" || t.Name() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// encodeStructured moves event attributes from the metadata into a JSON envelope with the data.
+func encodeStructured(msg *pubee.Message) ([]byte, error) {
+	envelope := map[string]interface{}{}
+	meta := map[string]string{codec.ContentTypeKey: ContentType}
+
+	for k, v := range msg.Metadata {
+		switch {
+		case strings.HasPrefix(k, MetadataPrefix):
+			envelope[strings.TrimPrefix(k, MetadataPrefix)] = v
+		case k == codec.ContentTypeKey:
+			envelope["datacontenttype"] = v
+		default:
+			meta[k] = v
+		}
+	}
+
+	if msg.Data != nil {
+		ct, _ := envelope["datacontenttype"].(string)
+		switch {
+		case isJSON(ct) && json.Valid(msg.Data):
+			envelope["data"] = json.RawMessage(msg.Data)
+		case strings.HasPrefix(ct, "text/"):
+			envelope["data"] = string(msg.Data)
+		default:
+			envelope["data_base64"] = base64.StdEncoding.EncodeToString(msg.Data)
+		}
+	}
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("cloudevents: failed to encode an envelope: %w", err)
+	}
+
+	msg.Metadata = meta
+
+	return data, nil
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || mt == "text/json" || strings.HasSuffix(mt, "+json")
+}
+
+func setDefault(meta map[string]string, attr, value string) {
+	if _, ok := meta[MetadataPrefix+attr]; !ok {
+		meta[MetadataPrefix+attr] = value
+	}
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
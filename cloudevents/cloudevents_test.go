@@ -0,0 +1,194 @@
+package cloudevents_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/cloudevents"
+	"github.com/izumin5210/pubee/codec"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+type BookCreated struct {
+	Title string `json:"title"`
+}
+
+func TestWithEnvelope_Binary(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver,
+		cloudevents.WithEnvelope(
+			cloudevents.WithSource("/books"),
+			cloudevents.WithIDFunc(func() string { return "awesomeid" }),
+		),
+	)
+
+	publisher.Publish(ctx, &BookCreated{Title: "The Go Programming Language"})
+	publisher.Publish(ctx, &wrappers.StringValue{Value: "test"}, pubee.WithProtobuf())
+	publisher.Publish(ctx, "test", cloudevents.WithEventType("com.example.test"), cloudevents.WithExtension("foo", "bar"))
+	publisher.Close(ctx)
+
+	msgs := driver.Messages()
+	if got, want := len(msgs), 3; got != want {
+		t.Fatalf("Published %d messages, want %d", got, want)
+	}
+
+	ev, err := cloudevents.Decode(&pubee.ReceivedMessage{Data: msgs[0].Data, Metadata: msgs[0].Metadata})
+	if err != nil {
+		t.Fatalf("Decode() returned %v", err)
+	}
+	if got, want := ev.SpecVersion, cloudevents.SpecVersion; got != want {
+		t.Errorf("Event has specversion %q, want %q", got, want)
+	}
+	if got, want := ev.ID, "awesomeid"; got != want {
+		t.Errorf("Event has id %q, want %q", got, want)
+	}
+	if got, want := ev.Source, "/books"; got != want {
+		t.Errorf("Event has source %q, want %q", got, want)
+	}
+	if got, want := ev.Type, "github.com/izumin5210/pubee/cloudevents_test.BookCreated"; got != want {
+		t.Errorf("Event has type %q, want %q", got, want)
+	}
+	if got, want := ev.DataContentType, "application/json"; got != want {
+		t.Errorf("Event has datacontenttype %q, want %q", got, want)
+	}
+	if ev.Time.IsZero() || time.Since(ev.Time) > time.Minute {
+		t.Errorf("Event has time %v, want the publish time", ev.Time)
+	}
+	if got, want := string(ev.Data), `{"title":"The Go Programming Language"}`; got != want {
+		t.Errorf("Event has data %s, want %s", got, want)
+	}
+
+	if got, want := msgs[1].Metadata["ce-type"], "google.protobuf.StringValue"; got != want {
+		t.Errorf("Event has type %q, want %q", got, want)
+	}
+
+	ev, err = cloudevents.Decode(&pubee.ReceivedMessage{Data: msgs[2].Data, Metadata: msgs[2].Metadata})
+	if err != nil {
+		t.Fatalf("Decode() returned %v", err)
+	}
+	if got, want := ev.Type, "com.example.test"; got != want {
+		t.Errorf("Event has type %q, want %q", got, want)
+	}
+	if got, want := ev.Extensions["foo"], "bar"; got != want {
+		t.Errorf("Event has extension foo=%q, want %q", got, want)
+	}
+}
+
+func TestWithEnvelope_Structured(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver,
+		pubee.WithMetadata("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"),
+		cloudevents.WithEnvelope(cloudevents.WithMode(cloudevents.Structured)),
+	)
+
+	publisher.Publish(ctx, &BookCreated{Title: "The Go Programming Language"}, pubee.WithTopic("books"))
+	publisher.Publish(ctx, []byte{0xde, 0xad, 0xbe, 0xef}, pubee.WithTopic("books"))
+	publisher.Close(ctx)
+
+	msgs := driver.Messages()
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Published %d messages, want %d", got, want)
+	}
+
+	if got, want := msgs[0].Metadata[codec.ContentTypeKey], cloudevents.ContentType; got != want {
+		t.Errorf("Message has content-type %q, want %q", got, want)
+	}
+	if _, ok := msgs[0].Metadata["ce-id"]; ok {
+		t.Error("Message should not have event attributes in metadata")
+	}
+	if got, want := msgs[0].Metadata["traceparent"], "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"; got != want {
+		t.Errorf("Message has traceparent %q, want %q", got, want)
+	}
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(msgs[0].Data, &envelope); err != nil {
+		t.Fatalf("Message data is not a JSON envelope: %v", err)
+	}
+	if got, want := envelope["source"], "books"; got != want {
+		t.Errorf("Envelope has source %v, want %v", got, want)
+	}
+	if got, want := envelope["data"], map[string]interface{}{"title": "The Go Programming Language"}; !equalJSON(got, want) {
+		t.Errorf("Envelope has data %v, want %v", got, want)
+	}
+
+	ev, err := cloudevents.Decode(&pubee.ReceivedMessage{Data: msgs[1].Data, Metadata: msgs[1].Metadata})
+	if err != nil {
+		t.Fatalf("Decode() returned %v", err)
+	}
+	if got, want := string(ev.Data), "\xde\xad\xbe\xef"; got != want {
+		t.Errorf("Event has data %q, want %q", got, want)
+	}
+	if got, want := ev.DataContentType, "application/octet-stream"; got != want {
+		t.Errorf("Event has datacontenttype %q, want %q", got, want)
+	}
+}
+
+func TestSubscribeInterceptor(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, cloudevents.WithEnvelope(cloudevents.WithMode(cloudevents.Structured)))
+	subscriber := pubee.NewSubscriber(driver.NewSubscriberDriver(memory.DefaultTopic),
+		pubee.WithSubscribeInterceptors(cloudevents.SubscribeInterceptor()),
+	)
+
+	type result struct {
+		book  BookCreated
+		event *cloudevents.Event
+		err   error
+	}
+	resultCh := make(chan result, 1)
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		var r result
+		r.err = msg.Unmarshal(&r.book)
+		if r.err == nil {
+			r.event, r.err = cloudevents.Decode(msg)
+		}
+		resultCh <- r
+		return nil
+	})
+	go subscriber.Subscribe(ctx)
+	defer subscriber.Close(ctx)
+
+	publisher.Publish(ctx, &BookCreated{Title: "The Go Programming Language"}, cloudevents.WithEventSubject("gopl"))
+	publisher.Close(ctx)
+
+	var r result
+	select {
+	case r = <-resultCh:
+	case <-time.After(time.Second):
+		t.Fatal("messages were not received")
+	}
+
+	if r.err != nil {
+		t.Fatalf("Handler got an error: %v", r.err)
+	}
+	if got, want := r.book.Title, "The Go Programming Language"; got != want {
+		t.Errorf("Received message has title %q, want %q", got, want)
+	}
+	if got, want := r.event.Subject, "gopl"; got != want {
+		t.Errorf("Event has subject %q, want %q", got, want)
+	}
+	if got, want := r.event.DataContentType, "application/json"; got != want {
+		t.Errorf("Event has datacontenttype %q, want %q", got, want)
+	}
+}
+
+func TestDecode_WhenNotCloudEvent(t *testing.T) {
+	_, err := cloudevents.Decode(&pubee.ReceivedMessage{Data: []byte("test"), Metadata: map[string]string{}})
+	if got, want := err, cloudevents.ErrNotCloudEvent; got != want {
+		t.Errorf("Decode() returned %v, want %v", got, want)
+	}
+}
+
+func equalJSON(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
@@ -0,0 +1,199 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/codec"
+)
+
+// ErrNotCloudEvent is returned when decoding a message that is not a CloudEvent.
+var ErrNotCloudEvent = errors.New("cloudevents: message is not a CloudEvent")
+
+// Event is a decoded CloudEvent.
+type Event struct {
+	SpecVersion     string
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	DataSchema      string
+	Extensions      map[string]string
+	Data            []byte
+}
+
+// Decode decodes a received message in either binary or structured mode.
+func Decode(m *pubee.ReceivedMessage) (*Event, error) {
+	if isStructured(m.Metadata[codec.ContentTypeKey]) {
+		return decodeStructured(m.Data)
+	}
+	return decodeBinary(m.Metadata, m.Data)
+}
+
+// SubscribeInterceptor returns a pubee.SubscribeInterceptor that converts structured mode messages into binary mode ones,
+// so that handlers can unmarshal message data with ReceivedMessage.Unmarshal regardless of the mode.
+func SubscribeInterceptor() pubee.SubscribeInterceptor {
+	return func(ctx context.Context, m *pubee.ReceivedMessage, h pubee.Handler) error {
+		if !isStructured(m.Metadata[codec.ContentTypeKey]) {
+			return h(ctx, m)
+		}
+
+		ev, err := decodeStructured(m.Data)
+		if err != nil {
+			return err
+		}
+
+		meta := make(map[string]string, len(m.Metadata)+len(ev.Extensions)+8)
+		for k, v := range m.Metadata {
+			if k != codec.ContentTypeKey {
+				meta[k] = v
+			}
+		}
+		for k, v := range ev.attributes() {
+			meta[MetadataPrefix+k] = v
+		}
+		if ev.DataContentType != "" {
+			meta[codec.ContentTypeKey] = ev.DataContentType
+		}
+
+		m.Metadata = meta
+		m.Data = ev.Data
+
+		return h(ctx, m)
+	}
+}
+
+func (ev *Event) attributes() map[string]string {
+	attrs := make(map[string]string, len(ev.Extensions)+7)
+	for k, v := range ev.Extensions {
+		attrs[k] = v
+	}
+	for k, v := range map[string]string{
+		"specversion": ev.SpecVersion,
+		"id":          ev.ID,
+		"source":      ev.Source,
+		"type":        ev.Type,
+		"subject":     ev.Subject,
+		"dataschema":  ev.DataSchema,
+	} {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	if !ev.Time.IsZero() {
+		attrs["time"] = ev.Time.Format(time.RFC3339Nano)
+	}
+	return attrs
+}
+
+func (ev *Event) set(attr, value string) error {
+	switch attr {
+	case "specversion":
+		ev.SpecVersion = value
+	case "id":
+		ev.ID = value
+	case "source":
+		ev.Source = value
+	case "type":
+		ev.Type = value
+	case "subject":
+		ev.Subject = value
+	case "datacontenttype":
+		ev.DataContentType = value
+	case "dataschema":
+		ev.DataSchema = value
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("cloudevents: invalid time %q: %w", value, err)
+		}
+		ev.Time = t
+	default:
+		if ev.Extensions == nil {
+			ev.Extensions = map[string]string{}
+		}
+		ev.Extensions[attr] = value
+	}
+	return nil
+}
+
+func decodeBinary(meta map[string]string, data []byte) (*Event, error) {
+	if _, ok := meta[MetadataPrefix+"specversion"]; !ok {
+		return nil, ErrNotCloudEvent
+	}
+
+	ev := &Event{
+		DataContentType: meta[codec.ContentTypeKey],
+		Data:            data,
+	}
+	for k, v := range meta {
+		if !strings.HasPrefix(k, MetadataPrefix) {
+			continue
+		}
+		if err := ev.set(strings.TrimPrefix(k, MetadataPrefix), v); err != nil {
+			return nil, err
+		}
+	}
+
+	return ev, nil
+}
+
+func decodeStructured(data []byte) (*Event, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("cloudevents: failed to decode an envelope: %w", err)
+	}
+	if _, ok := envelope["specversion"]; !ok {
+		return nil, ErrNotCloudEvent
+	}
+
+	ev := new(Event)
+	for k, raw := range envelope {
+		switch k {
+		case "data", "data_base64":
+			continue
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			v = string(raw)
+		}
+		if err := ev.set(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := envelope["data_base64"]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("cloudevents: invalid data_base64: %w", err)
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("cloudevents: invalid data_base64: %w", err)
+		}
+		ev.Data = data
+	} else if raw, ok := envelope["data"]; ok {
+		var s string
+		if !isJSON(ev.DataContentType) && json.Unmarshal(raw, &s) == nil {
+			ev.Data = []byte(s)
+		} else {
+			ev.Data = []byte(raw)
+		}
+	}
+
+	return ev, nil
+}
+
+func isStructured(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mt == ContentType
+}
@@ -0,0 +1,88 @@
+package cloudevents
+
+import (
+	"github.com/izumin5210/pubee"
+)
+
+// Mode is a content mode of CloudEvents.
+type Mode int
+
+const (
+	// Binary mode stores event attributes into message metadata with the "ce-" prefix,
+	// and leaves message data as is.
+	Binary Mode = iota
+	// Structured mode encodes event attributes and message data into a JSON envelope.
+	Structured
+)
+
+// Config represents CloudEvents configuration.
+type Config struct {
+	Mode     Mode
+	Source   string
+	IDFunc   func() string
+	TypeFunc func(interface{}) string
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is CloudEvents Option
+type Option func(*Config)
+
+// WithMode returns an Option that set the content mode. Defaults to Binary.
+func WithMode(m Mode) Option {
+	return func(c *Config) {
+		c.Mode = m
+	}
+}
+
+// WithSource returns an Option that set the default "source" attribute.
+// Defaults to the topic of messages.
+func WithSource(src string) Option {
+	return func(c *Config) {
+		c.Source = src
+	}
+}
+
+// WithIDFunc returns an Option that set a function generating "id" attributes. Defaults to random UUIDs.
+func WithIDFunc(f func() string) Option {
+	return func(c *Config) {
+		c.IDFunc = f
+	}
+}
+
+// WithTypeFunc returns an Option that set a function deriving "type" attributes from published bodies.
+// Defaults to full names of protobuf messages, or package paths and names of Go types.
+func WithTypeFunc(f func(interface{}) string) Option {
+	return func(c *Config) {
+		c.TypeFunc = f
+	}
+}
+
+// WithEventID returns a pubee.PublishOption that set the "id" attribute.
+func WithEventID(id string) pubee.PublishOption {
+	return pubee.WithMetadata(MetadataPrefix+"id", id)
+}
+
+// WithEventSource returns a pubee.PublishOption that set the "source" attribute.
+func WithEventSource(src string) pubee.PublishOption {
+	return pubee.WithMetadata(MetadataPrefix+"source", src)
+}
+
+// WithEventType returns a pubee.PublishOption that set the "type" attribute.
+func WithEventType(typ string) pubee.PublishOption {
+	return pubee.WithMetadata(MetadataPrefix+"type", typ)
+}
+
+// WithEventSubject returns a pubee.PublishOption that set the "subject" attribute.
+func WithEventSubject(subject string) pubee.PublishOption {
+	return pubee.WithMetadata(MetadataPrefix+"subject", subject)
+}
+
+// WithExtension returns a pubee.PublishOption that set an extension attribute.
+func WithExtension(name, value string) pubee.PublishOption {
+	return pubee.WithMetadata(MetadataPrefix+name, value)
+}
@@ -0,0 +1,102 @@
+// Package codec provides encodings of message payloads with their content types.
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ContentTypeKey is the metadata key the content type of a message is stored to.
+const ContentTypeKey = "content-type"
+
+// Codec marshals and unmarshals message payloads of a content type.
+type Codec interface {
+	ContentType() string
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte, interface{}) error
+}
+
+var (
+	// JSON is a Codec for "application/json".
+	JSON Codec = jsonCodec{}
+	// Protobuf is a Codec for "application/protobuf". Values should implement proto.Message.
+	Protobuf Codec = protobufCodec{}
+	// Text is a Codec for "text/plain". Values should be string or []byte.
+	Text Codec = rawCodec{contentType: "text/plain; charset=utf-8"}
+	// Raw is a Codec for "application/octet-stream". Values should be string or []byte.
+	Raw Codec = rawCodec{contentType: "application/octet-stream"}
+)
+
+// Default returns a Codec for the value: Text for string, Raw for []byte, and JSON for others.
+func Default(in interface{}) Codec {
+	switch in.(type) {
+	case string:
+		return Text
+	case []byte:
+		return Raw
+	default:
+		return JSON
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Marshal(in interface{}) ([]byte, error) {
+	return json.Marshal(in)
+}
+
+func (jsonCodec) Unmarshal(data []byte, out interface{}) error {
+	return json.Unmarshal(data, out)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return "application/protobuf" }
+
+func (protobufCodec) Marshal(in interface{}) ([]byte, error) {
+	if m, ok := in.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+	return nil, errors.New("message should implement proto.Message interface")
+}
+
+func (protobufCodec) Unmarshal(data []byte, out interface{}) error {
+	if m, ok := out.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	return errors.New("message should implement proto.Message interface")
+}
+
+type rawCodec struct {
+	contentType string
+}
+
+func (c rawCodec) ContentType() string { return c.contentType }
+
+func (rawCodec) Marshal(in interface{}) ([]byte, error) {
+	switch v := in.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("message should be string or []byte, but got %T", in)
+	}
+}
+
+func (rawCodec) Unmarshal(data []byte, out interface{}) error {
+	switch v := out.(type) {
+	case *string:
+		*v = string(data)
+	case *[]byte:
+		*v = data
+	default:
+		return fmt.Errorf("message should be unmarshaled into *string or *[]byte, but got %T", out)
+	}
+	return nil
+}
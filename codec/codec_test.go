@@ -0,0 +1,70 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/izumin5210/pubee/codec"
+)
+
+func TestDefault(t *testing.T) {
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{in: "foo", want: "text/plain; charset=utf-8"},
+		{in: []byte("foo"), want: "application/octet-stream"},
+		{in: map[string]string{"foo": "bar"}, want: "application/json"},
+	}
+
+	for _, tc := range cases {
+		if got, want := codec.Default(tc.in).ContentType(), tc.want; got != want {
+			t.Errorf("Default(%v) returned a codec for %q, want %q", tc.in, got, want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := codec.DefaultRegistry
+
+	cases := []struct {
+		contentType string
+		want        codec.Codec
+	}{
+		{contentType: "application/json", want: codec.JSON},
+		{contentType: "application/json; charset=utf-8", want: codec.JSON},
+		{contentType: "application/protobuf", want: codec.Protobuf},
+		{contentType: "text/plain", want: codec.Text},
+		{contentType: "application/octet-stream", want: codec.Raw},
+		{contentType: "application/xml"},
+		{contentType: ""},
+	}
+
+	for _, tc := range cases {
+		got, ok := r.Lookup(tc.contentType)
+		if tc.want == nil {
+			if ok {
+				t.Errorf("Lookup(%q) returned %v, want nothing", tc.contentType, got)
+			}
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Lookup(%q) returned %v, want %v", tc.contentType, got, tc.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := codec.JSON.Marshal(map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("Marshal() returned %v", err)
+	}
+
+	var out map[string]string
+	err = codec.JSON.Unmarshal(data, &out)
+	if err != nil {
+		t.Fatalf("Unmarshal() returned %v", err)
+	}
+	if got, want := out["foo"], "bar"; got != want {
+		t.Errorf("Unmarshal() decoded %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package codec
+
+import (
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Registry holds Codecs by their content types.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+// DefaultRegistry contains JSON, Protobuf, Text and Raw codecs.
+var DefaultRegistry = NewRegistry(JSON, Protobuf, Text, Raw)
+
+func NewRegistry(codecs ...Codec) *Registry {
+	r := &Registry{codecs: map[string]Codec{}}
+	for _, c := range codecs {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register adds the Codec. It replaces a Codec registered for the same media type.
+func (r *Registry) Register(c Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[mediaType(c.ContentType())] = c
+}
+
+// Lookup returns a Codec for the content type. Parameters of the content type, such as charset, are ignored.
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codecs[mediaType(contentType)]
+	return c, ok
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mt
+}
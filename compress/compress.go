@@ -0,0 +1,139 @@
+// Package compress compresses marshaled message data, and decompresses received message data.
+//
+// The algorithm is stored into the "content-encoding" metadata, so that consumers can decompress data
+// with Decompress or SubscribeInterceptor.
+package compress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/izumin5210/pubee"
+)
+
+// EncodingKey is the metadata key of the compression algorithm.
+const EncodingKey = "content-encoding"
+
+// ErrUnknownEncoding is returned when decompressing data with an unregistered encoding.
+var ErrUnknownEncoding = errors.New("compress: unknown encoding")
+
+// WithCompression returns a pubee.Option that compresses message data with the Compressor.
+func WithCompression(c Compressor, opts ...Option) pubee.Option {
+	return pubee.WithPublishInterceptors(PublishInterceptor(c, opts...))
+}
+
+// PublishInterceptor returns a pubee.PublishInterceptor that compresses message data with the Compressor.
+// Messages smaller than the minimum size, already encoded, or not getting smaller are published as is.
+func PublishInterceptor(c Compressor, opts ...Option) pubee.PublishInterceptor {
+	cfg := newConfig(opts)
+
+	return func(ctx context.Context, msg *pubee.Message, h pubee.PublishHandler) <-chan error {
+		if len(msg.Data) < cfg.MinSize {
+			return h(ctx, msg)
+		}
+		if _, ok := msg.Metadata[EncodingKey]; ok {
+			return h(ctx, msg)
+		}
+
+		data, err := c.Compress(msg.Data)
+		if err != nil {
+			errCh := make(chan error, 1)
+			errCh <- fmt.Errorf("compress: failed to compress data with %s: %w", c.Encoding(), err)
+			close(errCh)
+			return errCh
+		}
+		if len(data) >= len(msg.Data) {
+			return h(ctx, msg)
+		}
+
+		md := make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			md[k] = v
+		}
+		md[EncodingKey] = c.Encoding()
+
+		msg.Data = data
+		msg.Metadata = md
+
+		return h(ctx, msg)
+	}
+}
+
+var (
+	mu          sync.RWMutex
+	compressors = map[string]Compressor{}
+)
+
+func init() {
+	Register(Gzip)
+	Register(Zstd)
+	Register(Snappy)
+}
+
+// Register registers the Compressor for decompression. Gzip, Zstd and Snappy are registered by default.
+func Register(c Compressor) {
+	mu.Lock()
+	defer mu.Unlock()
+	compressors[c.Encoding()] = c
+}
+
+// Lookup returns a registered Compressor for the encoding.
+func Lookup(encoding string) (Compressor, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := compressors[encoding]
+	return c, ok
+}
+
+// Decompress decompresses data with the Compressor for the encoding.
+// Data are returned as is when the encoding is empty or "identity".
+// It returns ErrTooLarge when decompressed data exceed the limit set by WithMaxDecompressedSize.
+func Decompress(data []byte, encoding string, opts ...Option) ([]byte, error) {
+	cfg := newConfig(opts)
+
+	if encoding == "" || encoding == "identity" {
+		return data, nil
+	}
+	c, ok := Lookup(encoding)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncoding, encoding)
+	}
+	return c.Decompress(data, cfg.MaxDecompressedSize)
+}
+
+// DecompressMessage decompresses the message data in place, and removes the encoding from the metadata.
+func DecompressMessage(m *pubee.ReceivedMessage, opts ...Option) error {
+	encoding, ok := m.Metadata[EncodingKey]
+	if !ok {
+		return nil
+	}
+
+	data, err := Decompress(m.Data, encoding, opts...)
+	if err != nil {
+		return err
+	}
+
+	md := make(map[string]string, len(m.Metadata))
+	for k, v := range m.Metadata {
+		if k != EncodingKey {
+			md[k] = v
+		}
+	}
+
+	m.Data = data
+	m.Metadata = md
+
+	return nil
+}
+
+// SubscribeInterceptor returns a pubee.SubscribeInterceptor that decompresses received messages before handlers.
+func SubscribeInterceptor(opts ...Option) pubee.SubscribeInterceptor {
+	return func(ctx context.Context, m *pubee.ReceivedMessage, h pubee.Handler) error {
+		if err := DecompressMessage(m, opts...); err != nil {
+			return err
+		}
+		return h(ctx, m)
+	}
+}
@@ -0,0 +1,152 @@
+package compress_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/compress"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+type Document struct {
+	Body string `json:"body"`
+}
+
+func TestWithCompression(t *testing.T) {
+	doc := &Document{Body: strings.Repeat("pubee ", 1000)}
+
+	for _, c := range []compress.Compressor{compress.Gzip, compress.Zstd, compress.Snappy} {
+		t.Run(c.Encoding(), func(t *testing.T) {
+			ctx := context.Background()
+			driver := memory.NewDriver()
+			publisher := pubee.New(driver, compress.WithCompression(c))
+
+			publisher.Publish(ctx, doc)
+			publisher.Publish(ctx, &Document{Body: "small"})
+			publisher.Close(ctx)
+
+			msgs := driver.Messages()
+			if got, want := len(msgs), 2; got != want {
+				t.Fatalf("Published %d messages, want %d", got, want)
+			}
+
+			if got, want := msgs[0].Metadata[compress.EncodingKey], c.Encoding(); got != want {
+				t.Errorf("Published message has encoding %q, want %q", got, want)
+			}
+			if got, limit := len(msgs[0].Data), len(doc.Body); got >= limit {
+				t.Errorf("Published message has %d bytes, want less than %d", got, limit)
+			}
+
+			m := &pubee.ReceivedMessage{Data: msgs[0].Data, Metadata: msgs[0].Metadata}
+			if err := compress.DecompressMessage(m); err != nil {
+				t.Fatalf("DecompressMessage() returned %v", err)
+			}
+			var got Document
+			if err := m.Unmarshal(&got); err != nil {
+				t.Fatalf("Unmarshal() returned %v", err)
+			}
+			if got.Body != doc.Body {
+				t.Error("Decompressed message differs from the published one")
+			}
+			if _, ok := m.Metadata[compress.EncodingKey]; ok {
+				t.Error("Decompressed message should not have an encoding")
+			}
+
+			if _, ok := msgs[1].Metadata[compress.EncodingKey]; ok {
+				t.Error("Messages smaller than the threshold should not be compressed")
+			}
+		})
+	}
+}
+
+func TestSubscribeInterceptor(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, compress.WithCompression(compress.Zstd, compress.WithMinSize(0)))
+	subscriber := pubee.NewSubscriber(driver.NewSubscriberDriver(memory.DefaultTopic),
+		pubee.WithSubscribeInterceptors(compress.SubscribeInterceptor()),
+	)
+
+	docCh := make(chan Document, 1)
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		var doc Document
+		err := msg.Unmarshal(&doc)
+		docCh <- doc
+		return err
+	})
+	go subscriber.Subscribe(ctx)
+	defer subscriber.Close(ctx)
+
+	publisher.Publish(ctx, &Document{Body: "compressed message compressed message"})
+	publisher.Close(ctx)
+
+	select {
+	case doc := <-docCh:
+		if got, want := doc.Body, "compressed message compressed message"; got != want {
+			t.Errorf("Received message has body %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("messages were not received")
+	}
+}
+
+func TestDecompress_WhenUnknownEncoding(t *testing.T) {
+	_, err := compress.Decompress([]byte("data"), "br")
+	if !errors.Is(err, compress.ErrUnknownEncoding) {
+		t.Errorf("Decompress() returned %v, want %v", err, compress.ErrUnknownEncoding)
+	}
+}
+
+func TestDecompress_WhenTooLarge(t *testing.T) {
+	data := []byte(strings.Repeat("pubee ", 1000))
+
+	for _, c := range []compress.Compressor{compress.Gzip, compress.Zstd, compress.Snappy} {
+		t.Run(c.Encoding(), func(t *testing.T) {
+			compressed, err := c.Compress(data)
+			if err != nil {
+				t.Fatalf("Compress() returned %v", err)
+			}
+
+			_, err = compress.Decompress(compressed, c.Encoding(), compress.WithMaxDecompressedSize(len(data)-1))
+			if !errors.Is(err, compress.ErrTooLarge) {
+				t.Errorf("Decompress() returned %v, want %v", err, compress.ErrTooLarge)
+			}
+
+			got, err := compress.Decompress(compressed, c.Encoding(), compress.WithMaxDecompressedSize(len(data)))
+			if err != nil {
+				t.Fatalf("Decompress() returned %v", err)
+			}
+			if string(got) != string(data) {
+				t.Error("Decompressed data differs from the original one")
+			}
+		})
+	}
+}
+
+func TestDecompress_WithInvalidMaxDecompressedSize(t *testing.T) {
+	data := []byte(strings.Repeat("pubee ", 1000))
+
+	for _, c := range []compress.Compressor{compress.Gzip, compress.Zstd, compress.Snappy} {
+		for _, n := range []int{0, -1} {
+			t.Run(fmt.Sprintf("%s/%d", c.Encoding(), n), func(t *testing.T) {
+				compressed, err := c.Compress(data)
+				if err != nil {
+					t.Fatalf("Compress() returned %v", err)
+				}
+
+				got, err := compress.Decompress(compressed, c.Encoding(), compress.WithMaxDecompressedSize(n))
+				if err != nil {
+					t.Fatalf("Decompress() returned %v", err)
+				}
+				if string(got) != string(data) {
+					t.Error("Decompressed data differs from the original one")
+				}
+			})
+		}
+	}
+}
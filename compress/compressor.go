@@ -0,0 +1,127 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrTooLarge is returned when decompressed data exceed the maximum size.
+var ErrTooLarge = errors.New("compress: decompressed data exceed the maximum size")
+
+// Compressor compresses and decompresses message data.
+type Compressor interface {
+	// Encoding returns a name stored into the "content-encoding" metadata.
+	Encoding() string
+	Compress([]byte) ([]byte, error)
+	// Decompress decompresses data, and returns ErrTooLarge before decompressed data exceed maxSize bytes.
+	// Data are received from untrusted producers, so implementations should not allocate more than maxSize.
+	Decompress(data []byte, maxSize int) ([]byte, error)
+}
+
+var (
+	Gzip   Compressor = gzipCompressor{}
+	Zstd   Compressor = zstdCompressor{}
+	Snappy Compressor = snappyCompressor{}
+)
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Encoding() string { return "gzip" }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte, maxSize int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readAll(r, maxSize)
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+)
+
+// initZstd creates an encoder shared by all messages. It is safe for concurrent use via EncodeAll.
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+	})
+}
+
+type zstdCompressor struct{}
+
+func (zstdCompressor) Encoding() string { return "zstd" }
+
+func (zstdCompressor) Compress(data []byte) ([]byte, error) {
+	initZstd()
+	return zstdEncoder.EncodeAll(data, nil), nil
+}
+
+// Decompress decodes data with a stream decoder, whose memory including the window is bounded by maxSize.
+func (zstdCompressor) Decompress(data []byte, maxSize int) ([]byte, error) {
+	r, err := zstd.NewReader(bytes.NewReader(data),
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(uint64(maxSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	out, err := readAll(r, maxSize)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, ErrTooLarge
+	}
+	return out, err
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) Encoding() string { return "snappy" }
+
+func (snappyCompressor) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCompressor) Decompress(data []byte, maxSize int) ([]byte, error) {
+	n, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxSize {
+		return nil, ErrTooLarge
+	}
+	return snappy.Decode(nil, data)
+}
+
+// readAll reads r until EOF, and returns ErrTooLarge when it has more than maxSize bytes.
+func readAll(r io.Reader, maxSize int) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
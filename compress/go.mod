@@ -0,0 +1,16 @@
+module github.com/izumin5210/pubee/compress
+
+go 1.25
+
+require (
+	github.com/golang/snappy v1.0.0
+	github.com/izumin5210/pubee v0.0.0
+	github.com/klauspost/compress v1.20.1
+)
+
+require (
+	github.com/golang/protobuf v1.4.2 // indirect
+	google.golang.org/protobuf v1.25.0 // indirect
+)
+
+replace github.com/izumin5210/pubee => ../
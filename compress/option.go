@@ -0,0 +1,46 @@
+package compress
+
+// Config represents compression configuration.
+type Config struct {
+	MinSize             int
+	MaxDecompressedSize int
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// DefaultMaxDecompressedSize is the default limit of decompressed data size.
+const DefaultMaxDecompressedSize = 64 << 20
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		MinSize:             1024,
+		MaxDecompressedSize: DefaultMaxDecompressedSize,
+	}
+	cfg.apply(opts)
+	if cfg.MaxDecompressedSize <= 0 {
+		cfg.MaxDecompressedSize = DefaultMaxDecompressedSize
+	}
+	return cfg
+}
+
+// Option is compression Option
+type Option func(*Config)
+
+// WithMinSize returns an Option that skips compressing messages smaller than n bytes. Defaults to 1024.
+func WithMinSize(n int) Option {
+	return func(c *Config) {
+		c.MinSize = n
+	}
+}
+
+// WithMaxDecompressedSize returns an Option that fails decompressing data larger than n bytes with ErrTooLarge,
+// to protect consumers from decompression bombs. Defaults to DefaultMaxDecompressedSize, and non-positive values are ignored.
+func WithMaxDecompressedSize(n int) Option {
+	return func(c *Config) {
+		c.MaxDecompressedSize = n
+	}
+}
@@ -0,0 +1,28 @@
+package pubee
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetter is a message that ultimately failed to be published.
+type DeadLetter struct {
+	// Message is the marshaled message before publish interceptors,
+	// so replaying it through the same interceptors reproduces the original publish.
+	Message *Message
+	// Err is the error returned by the last attempt.
+	Err error
+	// Attempts is the number of publish attempts including retries.
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeadLetterSink stores dead letters so that they can be inspected and replayed later.
+type DeadLetterSink interface {
+	Put(context.Context, *DeadLetter) error
+}
+
+// DeadLetterSinkFunc is a function implementing DeadLetterSink.
+type DeadLetterSinkFunc func(context.Context, *DeadLetter) error
+
+func (f DeadLetterSinkFunc) Put(ctx context.Context, dl *DeadLetter) error { return f(ctx, dl) }
@@ -0,0 +1,58 @@
+// Package deadletter provides pubee.DeadLetterSink implementations and replays dead letters.
+package deadletter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/codec"
+)
+
+// Source reads stored dead letters in the order they were put.
+type Source interface {
+	Each(ctx context.Context, f func(*pubee.DeadLetter) error) error
+}
+
+// Replay re-publishes dead letters read from src through the engine, and returns the number of replayed messages.
+// It stops at the first message failed to be published to keep the order.
+// Messages are published with their data, topic, ordering key and metadata before interceptors,
+// so they go through the interceptors of the engine again, such as compression, encryption and envelopes.
+// The source is not modified, so remove replayed dead letters from the source if needed.
+func Replay(ctx context.Context, src Source, e pubee.Engine, opts ...pubee.PublishOption) (int, error) {
+	var n int
+
+	err := src.Each(ctx, func(dl *pubee.DeadLetter) error {
+		msg := dl.Message
+
+		publishOpts := []pubee.PublishOption{pubee.WithCodec(codec.Raw)}
+		if msg.Topic != "" {
+			publishOpts = append(publishOpts, pubee.WithTopic(msg.Topic))
+		}
+		if msg.OrderingKey != "" {
+			publishOpts = append(publishOpts, pubee.WithOrderingKey(msg.OrderingKey))
+		}
+		if len(msg.Metadata) > 0 {
+			md := make(map[string]string, len(msg.Metadata))
+			for k, v := range msg.Metadata {
+				md[k] = v
+			}
+			publishOpts = append(publishOpts, pubee.WithMetadataMap(md))
+		}
+		publishOpts = append(publishOpts, opts...)
+
+		data := msg.Data
+		if data == nil {
+			data = []byte{}
+		}
+
+		if _, err := e.Publish(ctx, data, publishOpts...).Get(ctx); err != nil {
+			return fmt.Errorf("deadletter: failed to replay a message: %w", err)
+		}
+		n++
+
+		return nil
+	})
+
+	return n, err
+}
@@ -0,0 +1,236 @@
+package deadletter_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/deadletter"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+// publishFailures publishes messages with a driver that always fails, and puts them into the sink.
+func publishFailures(t *testing.T, sink pubee.DeadLetterSink, bodies ...string) {
+	t.Helper()
+
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(*pubee.Message) error { return errors.New("unavailable") })
+
+	publisher := pubee.New(driver,
+		pubee.WithTopic("awesometopic"),
+		pubee.WithOrderingKey("awesomekey"),
+		pubee.WithMetadata("foo", "bar"),
+		pubee.WithDeadLetterSink(sink),
+	)
+	for _, body := range bodies {
+		publisher.Publish(ctx, body).Get(ctx)
+	}
+	publisher.Close(ctx)
+}
+
+func testReplay(t *testing.T, src deadletter.Source) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var dls []*pubee.DeadLetter
+	err := src.Each(ctx, func(dl *pubee.DeadLetter) error {
+		dls = append(dls, dl)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each() returned %v", err)
+	}
+	if got, want := len(dls), 2; got != want {
+		t.Fatalf("Dead letters are %d, want %d", got, want)
+	}
+	if got, want := dls[0].Err.Error(), "unavailable"; got != want {
+		t.Errorf("Dead letter has error %q, want %q", got, want)
+	}
+	if got, want := dls[0].Attempts, 1; got != want {
+		t.Errorf("Dead letter has %d attempts, want %d", got, want)
+	}
+	if dls[0].FailedAt.IsZero() {
+		t.Error("Dead letter should have a failed time")
+	}
+
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver)
+	defer publisher.Close(ctx)
+
+	n, err := deadletter.Replay(ctx, src, publisher)
+	if err != nil {
+		t.Errorf("Replay() returned %v", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("Replay() returned %d, want %d", got, want)
+	}
+
+	msgs := driver.TopicMessages("awesometopic")
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Replayed messages are %d, want %d", got, want)
+	}
+	for i, body := range []string{"message1", "message2"} {
+		if got, want := string(msgs[i].Data), body; got != want {
+			t.Errorf("Replayed message has data %q, want %q", got, want)
+		}
+		if got, want := msgs[i].OrderingKey, "awesomekey"; got != want {
+			t.Errorf("Replayed message has ordering key %q, want %q", got, want)
+		}
+		md := map[string]string{"foo": "bar", "content-type": "text/plain; charset=utf-8"}
+		if got, want := msgs[i].Metadata, md; !reflect.DeepEqual(got, want) {
+			t.Errorf("Replayed message has metadata %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	sink, err := deadletter.OpenFile(filepath.Join(t.TempDir(), "deadletters.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenFile() returned %v", err)
+	}
+	defer sink.Close()
+
+	publishFailures(t, sink, "message1", "message2")
+	testReplay(t, sink)
+}
+
+func TestSQLSink(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "deadletters.db"))
+	if err != nil {
+		t.Fatalf("failed to open a database: %v", err)
+	}
+	defer db.Close()
+
+	if err := deadletter.CreateTable(ctx, db); err != nil {
+		t.Fatalf("CreateTable() returned %v", err)
+	}
+
+	sink := deadletter.NewSQLSink(db)
+
+	publishFailures(t, sink, "message1", "message2")
+	testReplay(t, sink)
+}
+
+func TestDriverSink(t *testing.T) {
+	driver := memory.NewDriver()
+	sink := deadletter.NewDriverSink(driver, "deadletters")
+
+	publishFailures(t, sink, "message1")
+
+	msgs := driver.TopicMessages("deadletters")
+	if got, want := len(msgs), 1; got != want {
+		t.Fatalf("Dead letters are %d, want %d", got, want)
+	}
+	if got, want := string(msgs[0].Data), "message1"; got != want {
+		t.Errorf("Dead letter has data %q, want %q", got, want)
+	}
+	for k, want := range map[string]string{
+		"foo":                       "bar",
+		deadletter.MetadataError:    "unavailable",
+		deadletter.MetadataAttempts: "1",
+		deadletter.MetadataTopic:    "awesometopic",
+	} {
+		if got := msgs[0].Metadata[k]; got != want {
+			t.Errorf("Dead letter has metadata %s=%q, want %q", k, got, want)
+		}
+	}
+	if _, ok := msgs[0].Metadata[deadletter.MetadataFailedAt]; !ok {
+		t.Errorf("Dead letter should have metadata %s", deadletter.MetadataFailedAt)
+	}
+}
+
+func TestReplay_WhenFailed(t *testing.T) {
+	ctx := context.Background()
+
+	sink, err := deadletter.OpenFile(filepath.Join(t.TempDir(), "deadletters.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenFile() returned %v", err)
+	}
+	defer sink.Close()
+
+	publishFailures(t, sink, "message1", "message2")
+
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if string(msg.Data) == "message2" {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	publisher := pubee.New(driver)
+	defer publisher.Close(ctx)
+
+	n, err := deadletter.Replay(ctx, sink, publisher)
+	if err == nil {
+		t.Error("Replay() should return an error")
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("Replay() returned %d, want %d", got, want)
+	}
+}
+
+func TestReplay_WithInterceptors(t *testing.T) {
+	ctx := context.Background()
+
+	// wrap emulates a data-transforming interceptor such as compression or encryption.
+	wrap := pubee.WithPublishInterceptors(func(ctx context.Context, msg *pubee.Message, h pubee.PublishHandler) <-chan error {
+		msg.Data = append([]byte("wrapped:"), msg.Data...)
+		msg.Metadata["wrapped"] = "true"
+		return h(ctx, msg)
+	})
+
+	var dls []*pubee.DeadLetter
+	failing := memory.NewDriver()
+	failing.InjectFailure(func(*pubee.Message) error { return errors.New("unavailable") })
+	publisher := pubee.New(failing, wrap, pubee.WithDeadLetterSink(pubee.DeadLetterSinkFunc(func(ctx context.Context, dl *pubee.DeadLetter) error {
+		dls = append(dls, dl)
+		return nil
+	})))
+	publisher.Publish(ctx, "message1").Get(ctx)
+	publisher.Close(ctx)
+
+	if got, want := len(dls), 1; got != want {
+		t.Fatalf("Dead letters are %d, want %d", got, want)
+	}
+	if got, want := string(dls[0].Message.Data), "message1"; got != want {
+		t.Errorf("Dead letter has data %q, want %q", got, want)
+	}
+	if _, ok := dls[0].Message.Metadata["wrapped"]; ok {
+		t.Error("Dead letter should have metadata before interceptors")
+	}
+
+	driver := memory.NewDriver()
+	publisher = pubee.New(driver, wrap)
+	defer publisher.Close(ctx)
+
+	src := sourceFunc(func(ctx context.Context, f func(*pubee.DeadLetter) error) error {
+		for _, dl := range dls {
+			if err := f(dl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if _, err := deadletter.Replay(ctx, src, publisher); err != nil {
+		t.Fatalf("Replay() returned %v", err)
+	}
+	if got, want := string(driver.Messages()[0].Data), "wrapped:message1"; got != want {
+		t.Errorf("Replayed message has data %q, want %q", got, want)
+	}
+}
+
+type sourceFunc func(ctx context.Context, f func(*pubee.DeadLetter) error) error
+
+func (f sourceFunc) Each(ctx context.Context, g func(*pubee.DeadLetter) error) error {
+	return f(ctx, g)
+}
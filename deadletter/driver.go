@@ -0,0 +1,53 @@
+package deadletter
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Metadata keys set to messages published by DriverSink.
+const (
+	MetadataError    = "deadletter-error"
+	MetadataAttempts = "deadletter-attempts"
+	MetadataTopic    = "deadletter-topic"
+	MetadataFailedAt = "deadletter-failed-at"
+)
+
+// DriverSink is a pubee.DeadLetterSink that publishes dead letters with another pubee.Driver, such as a dead-letter topic.
+// The error, the number of attempts, the original topic and the failed time are stored into the metadata.
+type DriverSink struct {
+	driver pubee.Driver
+	topic  string
+}
+
+var _ pubee.DeadLetterSink = (*DriverSink)(nil)
+
+// NewDriverSink creates a DriverSink that publishes dead letters to topic.
+// The driver's default topic is used when topic is empty.
+func NewDriverSink(d pubee.Driver, topic string) *DriverSink {
+	return &DriverSink{driver: d, topic: topic}
+}
+
+// Put publishes the dead letter and waits for the result.
+func (s *DriverSink) Put(ctx context.Context, dl *pubee.DeadLetter) error {
+	md := make(map[string]string, len(dl.Message.Metadata)+4)
+	for k, v := range dl.Message.Metadata {
+		md[k] = v
+	}
+	if dl.Err != nil {
+		md[MetadataError] = dl.Err.Error()
+	}
+	md[MetadataAttempts] = strconv.Itoa(dl.Attempts)
+	md[MetadataTopic] = dl.Message.Topic
+	md[MetadataFailedAt] = dl.FailedAt.UTC().Format(time.RFC3339Nano)
+
+	return <-s.driver.Publish(ctx, &pubee.Message{
+		Topic:       s.topic,
+		OrderingKey: dl.Message.OrderingKey,
+		Data:        dl.Message.Data,
+		Metadata:    md,
+	})
+}
@@ -0,0 +1,129 @@
+package deadletter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// record is a JSON representation of a dead letter.
+type record struct {
+	ID          string            `json:"id,omitempty"`
+	Topic       string            `json:"topic,omitempty"`
+	OrderingKey string            `json:"ordering_key,omitempty"`
+	Data        []byte            `json:"data"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Error       string            `json:"error"`
+	Attempts    int               `json:"attempts"`
+	FailedAt    time.Time         `json:"failed_at"`
+}
+
+func newRecord(dl *pubee.DeadLetter) *record {
+	r := &record{
+		ID:          dl.Message.ID,
+		Topic:       dl.Message.Topic,
+		OrderingKey: dl.Message.OrderingKey,
+		Data:        dl.Message.Data,
+		Metadata:    dl.Message.Metadata,
+		Attempts:    dl.Attempts,
+		FailedAt:    dl.FailedAt,
+	}
+	if dl.Err != nil {
+		r.Error = dl.Err.Error()
+	}
+	return r
+}
+
+func (r *record) deadLetter() *pubee.DeadLetter {
+	return &pubee.DeadLetter{
+		Message: &pubee.Message{
+			ID:          r.ID,
+			Topic:       r.Topic,
+			OrderingKey: r.OrderingKey,
+			Data:        r.Data,
+			Metadata:    r.Metadata,
+		},
+		Err:      errors.New(r.Error),
+		Attempts: r.Attempts,
+		FailedAt: r.FailedAt,
+	}
+}
+
+// File is a pubee.DeadLetterSink that appends dead letters to a local file as JSON lines.
+type File struct {
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+var (
+	_ pubee.DeadLetterSink = (*File)(nil)
+	_ Source               = (*File)(nil)
+)
+
+// OpenFile opens the file at path for appending dead letters. The file is created if it does not exist.
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{path: path, f: f}, nil
+}
+
+// Put appends the dead letter and syncs the file.
+func (f *File) Put(ctx context.Context, dl *pubee.DeadLetter) error {
+	line, err := json.Marshal(newRecord(dl))
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.f.Sync()
+}
+
+// Each reads dead letters from the beginning of the file.
+func (f *File) Each(ctx context.Context, fn func(*pubee.DeadLetter) error) error {
+	r, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 64*1024*1024)
+	for s.Scan() {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(rec.deadLetter()); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Close()
+}
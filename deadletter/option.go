@@ -0,0 +1,39 @@
+package deadletter
+
+// Config represents SQLSink configuration.
+type Config struct {
+	Table   string
+	Dialect Dialect
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		Table:   "pubee_dead_letters",
+		Dialect: SQLite,
+	}
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is SQLSink Option
+type Option func(*Config)
+
+// WithTable returns an Option that set a table name dead letters are stored to. Defaults to "pubee_dead_letters".
+func WithTable(name string) Option {
+	return func(c *Config) {
+		c.Table = name
+	}
+}
+
+// WithDialect returns an Option that set a Dialect of the database. Defaults to SQLite.
+func WithDialect(d Dialect) Option {
+	return func(c *Config) {
+		c.Dialect = d
+	}
+}
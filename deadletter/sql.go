@@ -0,0 +1,177 @@
+package deadletter
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Dialect represents differences of SQL between databases.
+type Dialect struct {
+	// Placeholder returns a bind parameter for the n-th (1-origin) argument.
+	Placeholder func(n int) string
+	// CreateTable is a statement to create a dead-letter table. `%s` is replaced with the table name.
+	CreateTable string
+}
+
+var (
+	SQLite = Dialect{
+		Placeholder: questionPlaceholder,
+		CreateTable: `CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message_id TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	ordering_key TEXT NOT NULL,
+	data BLOB NOT NULL,
+	metadata TEXT NOT NULL,
+	error TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	failed_at INTEGER NOT NULL
+)`,
+	}
+
+	MySQL = Dialect{
+		Placeholder: questionPlaceholder,
+		CreateTable: `CREATE TABLE IF NOT EXISTS %s (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	message_id VARCHAR(255) NOT NULL,
+	topic VARCHAR(255) NOT NULL,
+	ordering_key VARCHAR(255) NOT NULL,
+	data LONGBLOB NOT NULL,
+	metadata TEXT NOT NULL,
+	error TEXT NOT NULL,
+	attempts INT NOT NULL,
+	failed_at BIGINT NOT NULL
+)`,
+	}
+
+	Postgres = Dialect{
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		CreateTable: `CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	message_id TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	ordering_key TEXT NOT NULL,
+	data BYTEA NOT NULL,
+	metadata TEXT NOT NULL,
+	error TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	failed_at BIGINT NOT NULL
+)`,
+	}
+)
+
+func questionPlaceholder(int) string { return "?" }
+
+// CreateTable creates a dead-letter table when it does not exist.
+func CreateTable(ctx context.Context, db *sql.DB, opts ...Option) error {
+	cfg := newConfig(opts)
+	_, err := db.ExecContext(ctx, fmt.Sprintf(cfg.Dialect.CreateTable, cfg.Table))
+	return err
+}
+
+// SQLSink is a pubee.DeadLetterSink that inserts dead letters into a database/sql table.
+// The table can be created with CreateTable.
+type SQLSink struct {
+	db  *sql.DB
+	cfg *Config
+}
+
+var (
+	_ pubee.DeadLetterSink = (*SQLSink)(nil)
+	_ Source               = (*SQLSink)(nil)
+)
+
+func NewSQLSink(db *sql.DB, opts ...Option) *SQLSink {
+	return &SQLSink{db: db, cfg: newConfig(opts)}
+}
+
+func (s *SQLSink) Put(ctx context.Context, dl *pubee.DeadLetter) error {
+	md, err := json.Marshal(dl.Message.Metadata)
+	if err != nil {
+		return err
+	}
+
+	var errMsg string
+	if dl.Err != nil {
+		errMsg = dl.Err.Error()
+	}
+
+	data := dl.Message.Data
+	if data == nil {
+		data = []byte{}
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		fmt.Sprintf(
+			"INSERT INTO %s (message_id, topic, ordering_key, data, metadata, error, attempts, failed_at) VALUES (%s)",
+			s.cfg.Table, s.placeholders(8),
+		),
+		dl.Message.ID, dl.Message.Topic, dl.Message.OrderingKey, data, string(md), errMsg, dl.Attempts, dl.FailedAt.UnixNano(),
+	)
+	return err
+}
+
+// Each reads dead letters in the order they were inserted.
+// All rows are read before fn is called, so fn can write to the same database.
+func (s *SQLSink) Each(ctx context.Context, fn func(*pubee.DeadLetter) error) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT message_id, topic, ordering_key, data, metadata, error, attempts, failed_at FROM %s ORDER BY id",
+		s.cfg.Table,
+	))
+	if err != nil {
+		return err
+	}
+
+	var dls []*pubee.DeadLetter
+	for rows.Next() {
+		var (
+			msg      pubee.Message
+			md       string
+			errMsg   string
+			attempts int
+			failedAt int64
+		)
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.OrderingKey, &msg.Data, &md, &errMsg, &attempts, &failedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(md), &msg.Metadata); err != nil {
+			rows.Close()
+			return err
+		}
+		dls = append(dls, &pubee.DeadLetter{
+			Message:  &msg,
+			Err:      errors.New(errMsg),
+			Attempts: attempts,
+			FailedAt: time.Unix(0, failedAt),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, dl := range dls {
+		if err := fn(dl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLSink) placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = s.cfg.Dialect.Placeholder(i + 1)
+	}
+	return strings.Join(ps, ", ")
+}
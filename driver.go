@@ -3,7 +3,15 @@ package pubee
 import "context"
 
 type Driver interface {
+	// Publish publishes the message, and reports the result to the returned channel.
+	// The context is canceled after the result is reported, or when the message is dropped by flow control,
+	// so drivers should give up publishing when it is done.
 	Publish(context.Context, *Message) <-chan error
 	Flush()
 	Close(context.Context) error
 }
+
+type SubscriberDriver interface {
+	Receive(context.Context, func(context.Context, *ReceivedMessage)) error
+	Close(context.Context) error
+}
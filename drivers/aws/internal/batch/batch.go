@@ -0,0 +1,162 @@
+// Package batch buffers messages and sends them with the AWS batch APIs.
+package batch
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/izumin5210/pubee"
+	pubeebatch "github.com/izumin5210/pubee/internal/batch"
+)
+
+const (
+	// MaxEntries is the maximum number of entries in a PublishBatch or SendMessageBatch request.
+	MaxEntries = 10
+	// MaxBytes is the maximum size of a message and of a whole batch request.
+	MaxBytes = 256 * 1024
+)
+
+// ErrMessageTooLarge is returned when a message exceeds MaxBytes.
+var ErrMessageTooLarge = errors.New("aws: message exceeds the 256KB size limit")
+
+// EntryError is an error reported for an entry of a batch request.
+type EntryError struct {
+	Code        string
+	Message     string
+	SenderFault bool
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("aws: %s: %s", e.Code, e.Message)
+}
+
+// Retryable reports whether the failure is caused by the server.
+func (e *EntryError) Retryable() bool {
+	return !e.SenderFault
+}
+
+const (
+	// EncodingKey is the message attribute set to EncodingBase64 when the body is encoded.
+	EncodingKey = "content-transfer-encoding"
+	// EncodingBase64 means the body is message data encoded in base64.
+	EncodingBase64 = "base64"
+)
+
+// Body returns the message body for message data.
+// AWS accepts only valid UTF-8 bodies with a limited set of characters, so other data are encoded in base64.
+func Body(data []byte) (body string, encoded bool) {
+	if isText(data) {
+		return string(data), false
+	}
+	return base64.StdEncoding.EncodeToString(data), true
+}
+
+// isText reports whether data consist of characters allowed in message bodies:
+// #x9 | #xA | #xD | #x20 to #xD7FF | #xE000 to #xFFFD | #x10000 to #x10FFFF
+func isText(data []byte) bool {
+	for len(data) > 0 {
+		r, n := utf8.DecodeRune(data)
+		if r == utf8.RuneError && n <= 1 {
+			return false
+		}
+		switch {
+		case r == 0x9, r == 0xA, r == 0xD:
+		case r >= 0x20 && r <= 0xD7FF:
+		case r >= 0xE000 && r <= 0xFFFD:
+		case r >= 0x10000 && r <= 0x10FFFF:
+		default:
+			return false
+		}
+		data = data[n:]
+	}
+	return true
+}
+
+// Entry is a buffered message.
+type Entry struct {
+	Dest  string
+	Msg   *pubee.Message
+	Body  string
+	Attrs map[string]string
+	Size  int
+	Err   error
+	ErrCh chan error
+}
+
+// NewEntry creates an Entry with the body and the attributes of the message.
+func NewEntry(dest string, msg *pubee.Message, errCh chan error) *Entry {
+	e := &Entry{Dest: dest, Msg: msg, Attrs: msg.Metadata, ErrCh: errCh}
+
+	var encoded bool
+	e.Body, encoded = Body(msg.Data)
+	if encoded {
+		e.Attrs = make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			e.Attrs[k] = v
+		}
+		e.Attrs[EncodingKey] = EncodingBase64
+	}
+
+	// AWS counts the body and the name, type and value of each attribute.
+	e.Size = len(e.Body)
+	for k, v := range e.Attrs {
+		e.Size += len(k) + len("String") + len(v)
+	}
+
+	return e
+}
+
+// SendFunc sends entries to dest. It sets Err of failed entries and Msg.ID of succeeded ones.
+// A returned error is reported for all entries.
+type SendFunc func(ctx context.Context, dest string, entries []*Entry) error
+
+// Batcher buffers entries and sends them in published order.
+type Batcher struct {
+	*pubeebatch.Batcher
+	send SendFunc
+}
+
+// New creates a Batcher that sends at most maxEntries entries at once, and sends buffered entries every timeout.
+// Non-positive values or more than MaxEntries entries fall back to MaxEntries, and a non-positive timeout to the default.
+func New(send SendFunc, maxEntries int, timeout time.Duration) *Batcher {
+	if maxEntries <= 0 || maxEntries > MaxEntries {
+		maxEntries = MaxEntries
+	}
+
+	b := &Batcher{send: send}
+	b.Batcher = pubeebatch.New(b.sendBatch, pubeebatch.Config{
+		MaxEntries: maxEntries,
+		MaxBytes:   MaxBytes,
+		Timeout:    timeout,
+	})
+
+	return b
+}
+
+// Add buffers the entry. It returns false if the Batcher is closed.
+func (b *Batcher) Add(e *Entry) bool {
+	return b.Batcher.Add(&pubeebatch.Entry{Key: e.Dest, Size: e.Size, Value: e})
+}
+
+func (b *Batcher) sendBatch(dest string, batch []*pubeebatch.Entry) {
+	entries := make([]*Entry, len(batch))
+	for i, e := range batch {
+		entries[i] = e.Value.(*Entry)
+	}
+
+	err := b.send(context.Background(), dest, entries)
+
+	for _, e := range entries {
+		switch {
+		case err != nil:
+			e.ErrCh <- err
+		case e.Err != nil:
+			e.ErrCh <- e.Err
+		}
+		close(e.ErrCh)
+	}
+}
@@ -0,0 +1,164 @@
+// Package sns provides a pubee.Driver for Amazon SNS.
+//
+// Messages are buffered and published with PublishBatch.
+// Message metadata are mapped to message attributes, and ordering keys are mapped to message group IDs of FIFO topics.
+// Message data are sent as strings. Data that are not valid text, such as protobuf, are encoded in base64
+// with the "content-transfer-encoding: base64" message attribute.
+package sns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/aws/internal/batch"
+)
+
+var (
+	// ErrClosed is returned when publishing messages to a closed Driver.
+	ErrClosed = errors.New("sns: driver is closed")
+	// ErrMessageTooLarge is returned when a message exceeds the 256KB size limit.
+	ErrMessageTooLarge = batch.ErrMessageTooLarge
+)
+
+// EncodingKey is the message attribute set to "base64" when message data are encoded in base64.
+const EncodingKey = batch.EncodingKey
+
+// EntryError is returned when SNS fails to publish a message in a batch.
+type EntryError = batch.EntryError
+
+// PublishBatchAPI publishes messages in a batch. *sns.Client implements it.
+type PublishBatchAPI interface {
+	PublishBatch(context.Context, *sns.PublishBatchInput, ...func(*sns.Options)) (*sns.PublishBatchOutput, error)
+}
+
+type Driver struct {
+	client          PublishBatchAPI
+	defaultTopicARN string
+	cfg             *Config
+	b               *batch.Batcher
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+// CreateDriver creates a Driver with the default AWS config.
+// Messages are published to topicARN by default, and to topic ARNs specified by pubee.WithTopic.
+func CreateDriver(ctx context.Context, topicARN string, opts ...Option) (*Driver, error) {
+	cfg := newConfig(opts)
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, cfg.LoadOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDriver(sns.NewFromConfig(awsCfg), topicARN, opts...), nil
+}
+
+// NewDriver creates a Driver that publishes messages with the client.
+func NewDriver(client PublishBatchAPI, topicARN string, opts ...Option) *Driver {
+	d := &Driver{
+		client:          client,
+		defaultTopicARN: topicARN,
+		cfg:             newConfig(opts),
+	}
+	d.b = batch.New(d.send, d.cfg.BatchSize, d.cfg.BatchTimeout)
+
+	return d
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	dest := msg.Topic
+	if dest == "" {
+		dest = d.defaultTopicARN
+	}
+	e := batch.NewEntry(dest, msg, errCh)
+
+	switch {
+	case e.Dest == "":
+		errCh <- errors.New("sns: topic is not specified")
+	case e.Size > batch.MaxBytes:
+		errCh <- fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, e.Size)
+	case !d.b.Add(e):
+		errCh <- ErrClosed
+	default:
+		return errCh
+	}
+
+	close(errCh)
+	return errCh
+}
+
+// Flush publishes all buffered messages and waits for their results.
+func (d *Driver) Flush() {
+	d.b.Flush()
+}
+
+func (d *Driver) Close(ctx context.Context) error {
+	d.b.Close()
+	return nil
+}
+
+func (d *Driver) send(ctx context.Context, topicARN string, entries []*batch.Entry) error {
+	in := &sns.PublishBatchInput{
+		TopicArn:                   aws.String(topicARN),
+		PublishBatchRequestEntries: make([]types.PublishBatchRequestEntry, len(entries)),
+	}
+	for i, e := range entries {
+		req := types.PublishBatchRequestEntry{
+			Id:      aws.String(strconv.Itoa(i)),
+			Message: aws.String(e.Body),
+		}
+		if key := e.Msg.OrderingKey; key != "" {
+			req.MessageGroupId = aws.String(key)
+		}
+		if len(e.Attrs) > 0 {
+			req.MessageAttributes = make(map[string]types.MessageAttributeValue, len(e.Attrs))
+			for k, v := range e.Attrs {
+				req.MessageAttributes[k] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String(v),
+				}
+			}
+		}
+		in.PublishBatchRequestEntries[i] = req
+	}
+
+	out, err := d.client.PublishBatch(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range out.Successful {
+		if e := lookup(entries, r.Id); e != nil {
+			e.Msg.ID = aws.ToString(r.MessageId)
+		}
+	}
+	for _, r := range out.Failed {
+		if e := lookup(entries, r.Id); e != nil {
+			e.Err = &EntryError{
+				Code:        aws.ToString(r.Code),
+				Message:     aws.ToString(r.Message),
+				SenderFault: r.SenderFault,
+			}
+		}
+	}
+
+	return nil
+}
+
+func lookup(entries []*batch.Entry, id *string) *batch.Entry {
+	i, err := strconv.Atoi(aws.ToString(id))
+	if err != nil || i < 0 || i >= len(entries) {
+		return nil
+	}
+	return entries[i]
+}
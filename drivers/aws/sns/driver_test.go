@@ -0,0 +1,367 @@
+package sns_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	"github.com/izumin5210/pubee"
+	pubeesns "github.com/izumin5210/pubee/drivers/aws/sns"
+)
+
+type publishedMessage struct {
+	TopicARN       string
+	Message        string
+	MessageGroupID string
+	Attributes     map[string]string
+}
+
+type batchResult struct {
+	XMLName    xml.Name      `xml:"PublishBatchResponse"`
+	Successful []resultEntry `xml:"PublishBatchResult>Successful>member"`
+	Failed     []resultEntry `xml:"PublishBatchResult>Failed>member"`
+}
+
+type resultEntry struct {
+	ID          string `xml:"Id"`
+	MessageID   string `xml:"MessageId,omitempty"`
+	Code        string `xml:"Code,omitempty"`
+	Message     string `xml:"Message,omitempty"`
+	SenderFault bool   `xml:"SenderFault,omitempty"`
+}
+
+// snstest is a stand-in for the PublishBatch API.
+type snstest struct {
+	Server *httptest.Server
+
+	mu       sync.Mutex
+	batches  [][]*publishedMessage
+	failFunc func(*publishedMessage) *resultEntry
+}
+
+func newSNSTest(t *testing.T) *snstest {
+	t.Helper()
+
+	st := new(snstest)
+	st.Server = httptest.NewServer(http.HandlerFunc(st.handle))
+
+	return st
+}
+
+func (st *snstest) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if got, want := r.Form.Get("Action"), "PublishBatch"; got != want {
+		http.Error(w, "unknown action: "+got, http.StatusBadRequest)
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var (
+		msgs []*publishedMessage
+		res  batchResult
+	)
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("PublishBatchRequestEntries.member.%d.", i)
+		id := r.Form.Get(prefix + "Id")
+		if id == "" {
+			break
+		}
+		msg := &publishedMessage{
+			TopicARN:       r.Form.Get("TopicArn"),
+			Message:        r.Form.Get(prefix + "Message"),
+			MessageGroupID: r.Form.Get(prefix + "MessageGroupId"),
+			Attributes:     parseAttributes(r.Form, prefix+"MessageAttributes.entry."),
+		}
+		msgs = append(msgs, msg)
+
+		if f := st.failFunc; f != nil {
+			if e := f(msg); e != nil {
+				e.ID = id
+				res.Failed = append(res.Failed, *e)
+				continue
+			}
+		}
+		res.Successful = append(res.Successful, resultEntry{ID: id, MessageID: fmt.Sprintf("msg-%d-%d", len(st.batches), i)})
+	}
+	st.batches = append(st.batches, msgs)
+
+	w.Header().Set("Content-Type", "text/xml")
+	xml.NewEncoder(w).Encode(res)
+}
+
+func parseAttributes(form url.Values, prefix string) map[string]string {
+	attrs := map[string]string{}
+	for i := 1; ; i++ {
+		name := form.Get(fmt.Sprintf("%s%d.Name", prefix, i))
+		if name == "" {
+			return attrs
+		}
+		attrs[name] = form.Get(fmt.Sprintf("%s%d.Value.StringValue", prefix, i))
+	}
+}
+
+func (st *snstest) Batches() [][]*publishedMessage {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.batches
+}
+
+func (st *snstest) Client(t *testing.T) *sns.Client {
+	t.Helper()
+
+	return sns.NewFromConfig(aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+	}, func(o *sns.Options) {
+		o.BaseEndpoint = aws.String(st.Server.URL)
+	})
+}
+
+func (st *snstest) Close() {
+	st.Server.Close()
+}
+
+func TestDriver(t *testing.T) {
+	st := newSNSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesns.NewDriver(st.Client(t), "arn:aws:sns:us-east-1:123456789012:awesometopic")
+	publisher := pubee.New(driver, pubee.WithMetadata("foo", "bar"))
+
+	id, err := publisher.Publish(ctx, "test message", pubee.WithOrderingKey("awesomekey")).Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+	if id == "" {
+		t.Error("Published message should have an ID")
+	}
+
+	_, err = publisher.Publish(ctx, "test message", pubee.WithTopic("arn:aws:sns:us-east-1:123456789012:othertopic")).Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+
+	publisher.Close(ctx)
+
+	var msgs []*publishedMessage
+	for _, b := range st.Batches() {
+		msgs = append(msgs, b...)
+	}
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Published messages are %d, want %d", got, want)
+	}
+	if got, want := msgs[0].TopicARN, "arn:aws:sns:us-east-1:123456789012:awesometopic"; got != want {
+		t.Errorf("Published message has topic %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Message, "test message"; got != want {
+		t.Errorf("Published message has body %q, want %q", got, want)
+	}
+	if got, want := msgs[0].MessageGroupID, "awesomekey"; got != want {
+		t.Errorf("Published message has group ID %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Attributes["foo"], "bar"; got != want {
+		t.Errorf("Published message has attribute foo=%q, want %q", got, want)
+	}
+	if got, want := msgs[1].TopicARN, "arn:aws:sns:us-east-1:123456789012:othertopic"; got != want {
+		t.Errorf("Published message has topic %q, want %q", got, want)
+	}
+
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), pubeesns.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestDriver_Batching(t *testing.T) {
+	st := newSNSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesns.NewDriver(st.Client(t), "arn:aws:sns:us-east-1:123456789012:awesometopic",
+		pubeesns.WithBatchSize(2),
+		pubeesns.WithBatchTimeout(time.Hour),
+	)
+
+	var errChs []<-chan error
+	for i := 0; i < 5; i++ {
+		errChs = append(errChs, driver.Publish(ctx, &pubee.Message{Data: []byte(fmt.Sprint(i))}))
+	}
+
+	if got, want := len(st.Batches()), 2; got > want {
+		t.Errorf("Published batches before Flush() are %d, want at most %d", got, want)
+	}
+
+	driver.Flush()
+
+	for _, errCh := range errChs {
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v", err)
+		}
+	}
+
+	var bodies []string
+	for _, b := range st.Batches() {
+		if got, want := len(b), 2; got > want {
+			t.Errorf("Published batch has %d messages, want at most %d", got, want)
+		}
+		for _, msg := range b {
+			bodies = append(bodies, msg.Message)
+		}
+	}
+	if got, want := strings.Join(bodies, ","), "0,1,2,3,4"; got != want {
+		t.Errorf("Published messages are %q, want %q", got, want)
+	}
+
+	driver.Close(ctx)
+}
+
+func TestDriver_WithInvalidBatchSize(t *testing.T) {
+	st := newSNSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesns.NewDriver(st.Client(t), "arn:aws:sns:us-east-1:123456789012:awesometopic",
+		pubeesns.WithBatchSize(0),
+		pubeesns.WithBatchTimeout(0),
+	)
+
+	errCh := driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	driver.Close(ctx)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := len(st.Batches()), 1; got != want {
+		t.Errorf("Published batches are %d, want %d", got, want)
+	}
+}
+
+func TestDriver_WhenEntryFailed(t *testing.T) {
+	st := newSNSTest(t)
+	defer st.Close()
+	st.failFunc = func(msg *publishedMessage) *resultEntry {
+		switch msg.Message {
+		case "invalid":
+			return &resultEntry{Code: "InvalidParameter", Message: "invalid message", SenderFault: true}
+		case "unavailable":
+			return &resultEntry{Code: "InternalError", Message: "internal error"}
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+
+	driver := pubeesns.NewDriver(st.Client(t), "arn:aws:sns:us-east-1:123456789012:awesometopic")
+	defer driver.Close(ctx)
+
+	errCh1 := driver.Publish(ctx, &pubee.Message{Data: []byte("valid")})
+	errCh2 := driver.Publish(ctx, &pubee.Message{Data: []byte("invalid")})
+	errCh3 := driver.Publish(ctx, &pubee.Message{Data: []byte("unavailable")})
+	driver.Flush()
+
+	if err := <-errCh1; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+
+	var entryErr *pubeesns.EntryError
+	err := <-errCh2
+	if !errors.As(err, &entryErr) {
+		t.Fatalf("Publish() returned %v, want an EntryError", err)
+	}
+	if got, want := entryErr.Code, "InvalidParameter"; got != want {
+		t.Errorf("EntryError has code %q, want %q", got, want)
+	}
+	if pubee.IsRetryable(err) {
+		t.Errorf("%v should not be retryable", err)
+	}
+
+	if err := <-errCh3; !pubee.IsRetryable(err) {
+		t.Errorf("%v should be retryable", err)
+	}
+}
+
+func TestDriver_WithBinaryData(t *testing.T) {
+	st := newSNSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesns.NewDriver(st.Client(t), "arn:aws:sns:us-east-1:123456789012:awesometopic")
+	defer driver.Close(ctx)
+
+	data := []byte{0x0a, 0x03, 0xff, 0x00}
+	for _, msg := range []*pubee.Message{
+		{Data: data, Metadata: map[string]string{"foo": "bar"}},
+		{Data: []byte("text message")},
+	} {
+		errCh := driver.Publish(ctx, msg)
+		driver.Flush()
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v", err)
+		}
+	}
+
+	var msgs []*publishedMessage
+	for _, b := range st.Batches() {
+		msgs = append(msgs, b...)
+	}
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Published messages are %d, want %d", got, want)
+	}
+	if got, want := msgs[0].Message, base64.StdEncoding.EncodeToString(data); got != want {
+		t.Errorf("Published message has body %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Attributes[pubeesns.EncodingKey], "base64"; got != want {
+		t.Errorf("Published message has attribute %s=%q, want %q", pubeesns.EncodingKey, got, want)
+	}
+	if got, want := msgs[0].Attributes["foo"], "bar"; got != want {
+		t.Errorf("Published message has attribute foo=%q, want %q", got, want)
+	}
+	if got, want := msgs[1].Message, "text message"; got != want {
+		t.Errorf("Published message has body %q, want %q", got, want)
+	}
+	if _, ok := msgs[1].Attributes[pubeesns.EncodingKey]; ok {
+		t.Errorf("Published text message should not have attribute %s", pubeesns.EncodingKey)
+	}
+}
+
+func TestDriver_WhenMessageTooLarge(t *testing.T) {
+	st := newSNSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesns.NewDriver(st.Client(t), "arn:aws:sns:us-east-1:123456789012:awesometopic")
+	defer driver.Close(ctx)
+
+	err := <-driver.Publish(ctx, &pubee.Message{Data: make([]byte, 256*1024+1)})
+	if !errors.Is(err, pubeesns.ErrMessageTooLarge) {
+		t.Errorf("Publish() returned %v, want %v", err, pubeesns.ErrMessageTooLarge)
+	}
+
+	driver.Flush()
+
+	if got, want := len(st.Batches()), 0; got != want {
+		t.Errorf("Published batches are %d, want %d", got, want)
+	}
+}
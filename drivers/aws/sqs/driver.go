@@ -0,0 +1,164 @@
+// Package sqs provides a pubee.Driver for Amazon SQS.
+//
+// Messages are buffered and sent with SendMessageBatch.
+// Message metadata are mapped to message attributes, and ordering keys are mapped to message group IDs of FIFO queues.
+// Message data are sent as strings. Data that are not valid text, such as protobuf, are encoded in base64
+// with the "content-transfer-encoding: base64" message attribute.
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/aws/internal/batch"
+)
+
+var (
+	// ErrClosed is returned when publishing messages to a closed Driver.
+	ErrClosed = errors.New("sqs: driver is closed")
+	// ErrMessageTooLarge is returned when a message exceeds the 256KB size limit.
+	ErrMessageTooLarge = batch.ErrMessageTooLarge
+)
+
+// EncodingKey is the message attribute set to "base64" when message data are encoded in base64.
+const EncodingKey = batch.EncodingKey
+
+// EntryError is returned when SQS fails to send a message in a batch.
+type EntryError = batch.EntryError
+
+// SendMessageBatchAPI sends messages in a batch. *sqs.Client implements it.
+type SendMessageBatchAPI interface {
+	SendMessageBatch(context.Context, *sqs.SendMessageBatchInput, ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+type Driver struct {
+	client          SendMessageBatchAPI
+	defaultQueueURL string
+	cfg             *Config
+	b               *batch.Batcher
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+// CreateDriver creates a Driver with the default AWS config.
+// Messages are sent to queueURL by default, and to queue URLs specified by pubee.WithTopic.
+func CreateDriver(ctx context.Context, queueURL string, opts ...Option) (*Driver, error) {
+	cfg := newConfig(opts)
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, cfg.LoadOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDriver(sqs.NewFromConfig(awsCfg), queueURL, opts...), nil
+}
+
+// NewDriver creates a Driver that sends messages with the client.
+func NewDriver(client SendMessageBatchAPI, queueURL string, opts ...Option) *Driver {
+	d := &Driver{
+		client:          client,
+		defaultQueueURL: queueURL,
+		cfg:             newConfig(opts),
+	}
+	d.b = batch.New(d.send, d.cfg.BatchSize, d.cfg.BatchTimeout)
+
+	return d
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	dest := msg.Topic
+	if dest == "" {
+		dest = d.defaultQueueURL
+	}
+	e := batch.NewEntry(dest, msg, errCh)
+
+	switch {
+	case e.Dest == "":
+		errCh <- errors.New("sqs: queue is not specified")
+	case e.Size > batch.MaxBytes:
+		errCh <- fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, e.Size)
+	case !d.b.Add(e):
+		errCh <- ErrClosed
+	default:
+		return errCh
+	}
+
+	close(errCh)
+	return errCh
+}
+
+// Flush sends all buffered messages and waits for their results.
+func (d *Driver) Flush() {
+	d.b.Flush()
+}
+
+func (d *Driver) Close(ctx context.Context) error {
+	d.b.Close()
+	return nil
+}
+
+func (d *Driver) send(ctx context.Context, queueURL string, entries []*batch.Entry) error {
+	in := &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(queueURL),
+		Entries:  make([]types.SendMessageBatchRequestEntry, len(entries)),
+	}
+	for i, e := range entries {
+		req := types.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(i)),
+			MessageBody: aws.String(e.Body),
+		}
+		if key := e.Msg.OrderingKey; key != "" {
+			req.MessageGroupId = aws.String(key)
+		}
+		if len(e.Attrs) > 0 {
+			req.MessageAttributes = make(map[string]types.MessageAttributeValue, len(e.Attrs))
+			for k, v := range e.Attrs {
+				req.MessageAttributes[k] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String(v),
+				}
+			}
+		}
+		in.Entries[i] = req
+	}
+
+	out, err := d.client.SendMessageBatch(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range out.Successful {
+		if e := lookup(entries, r.Id); e != nil {
+			e.Msg.ID = aws.ToString(r.MessageId)
+		}
+	}
+	for _, r := range out.Failed {
+		if e := lookup(entries, r.Id); e != nil {
+			e.Err = &EntryError{
+				Code:        aws.ToString(r.Code),
+				Message:     aws.ToString(r.Message),
+				SenderFault: r.SenderFault,
+			}
+		}
+	}
+
+	return nil
+}
+
+func lookup(entries []*batch.Entry, id *string) *batch.Entry {
+	i, err := strconv.Atoi(aws.ToString(id))
+	if err != nil || i < 0 || i >= len(entries) {
+		return nil
+	}
+	return entries[i]
+}
@@ -0,0 +1,368 @@
+package sqs_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/izumin5210/pubee"
+	pubeesqs "github.com/izumin5210/pubee/drivers/aws/sqs"
+)
+
+type sentMessage struct {
+	QueueURL       string
+	Message        string
+	MessageGroupID string
+	Attributes     map[string]string
+}
+
+type batchRequest struct {
+	QueueURL string `json:"QueueUrl"`
+	Entries  []struct {
+		ID                string `json:"Id"`
+		MessageBody       string
+		MessageGroupID    string `json:"MessageGroupId"`
+		MessageAttributes map[string]struct {
+			DataType    string
+			StringValue string
+		}
+	}
+}
+
+type batchResult struct {
+	Successful []resultEntry `json:"Successful"`
+	Failed     []resultEntry `json:"Failed"`
+}
+
+type resultEntry struct {
+	ID          string `json:"Id"`
+	MessageID   string `json:"MessageId,omitempty"`
+	Code        string `json:"Code,omitempty"`
+	Message     string `json:"Message,omitempty"`
+	SenderFault bool   `json:"SenderFault"`
+}
+
+// sqstest is a stand-in for the SendMessageBatch API.
+type sqstest struct {
+	Server *httptest.Server
+
+	mu       sync.Mutex
+	batches  [][]*sentMessage
+	failFunc func(*sentMessage) *resultEntry
+}
+
+func newSQSTest(t *testing.T) *sqstest {
+	t.Helper()
+
+	st := new(sqstest)
+	st.Server = httptest.NewServer(http.HandlerFunc(st.handle))
+
+	return st
+}
+
+func (st *sqstest) handle(w http.ResponseWriter, r *http.Request) {
+	if got, want := r.Header.Get("X-Amz-Target"), "AmazonSQS.SendMessageBatch"; got != want {
+		http.Error(w, "unknown target: "+got, http.StatusBadRequest)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var (
+		msgs []*sentMessage
+		res  = batchResult{Successful: []resultEntry{}, Failed: []resultEntry{}}
+	)
+	for i, e := range req.Entries {
+		msg := &sentMessage{
+			QueueURL:       req.QueueURL,
+			Message:        e.MessageBody,
+			MessageGroupID: e.MessageGroupID,
+			Attributes:     map[string]string{},
+		}
+		for k, v := range e.MessageAttributes {
+			msg.Attributes[k] = v.StringValue
+		}
+		msgs = append(msgs, msg)
+
+		if f := st.failFunc; f != nil {
+			if re := f(msg); re != nil {
+				re.ID = e.ID
+				res.Failed = append(res.Failed, *re)
+				continue
+			}
+		}
+		res.Successful = append(res.Successful, resultEntry{ID: e.ID, MessageID: fmt.Sprintf("msg-%d-%d", len(st.batches), i)})
+	}
+	st.batches = append(st.batches, msgs)
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (st *sqstest) Batches() [][]*sentMessage {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.batches
+}
+
+func (st *sqstest) Client(t *testing.T) *sqs.Client {
+	t.Helper()
+
+	return sqs.NewFromConfig(aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+	}, func(o *sqs.Options) {
+		o.BaseEndpoint = aws.String(st.Server.URL)
+		o.DisableMessageChecksumValidation = true
+	})
+}
+
+func (st *sqstest) Close() {
+	st.Server.Close()
+}
+
+func TestDriver(t *testing.T) {
+	st := newSQSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesqs.NewDriver(st.Client(t), "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue")
+	publisher := pubee.New(driver, pubee.WithMetadata("foo", "bar"))
+
+	id, err := publisher.Publish(ctx, "test message", pubee.WithOrderingKey("awesomekey")).Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+	if id == "" {
+		t.Error("Sent message should have an ID")
+	}
+
+	_, err = publisher.Publish(ctx, "test message", pubee.WithTopic("https://sqs.us-east-1.amazonaws.com/123456789012/otherqueue")).Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+
+	publisher.Close(ctx)
+
+	var msgs []*sentMessage
+	for _, b := range st.Batches() {
+		msgs = append(msgs, b...)
+	}
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Sent messages are %d, want %d", got, want)
+	}
+	if got, want := msgs[0].QueueURL, "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue"; got != want {
+		t.Errorf("Sent message has queue %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Message, "test message"; got != want {
+		t.Errorf("Sent message has body %q, want %q", got, want)
+	}
+	if got, want := msgs[0].MessageGroupID, "awesomekey"; got != want {
+		t.Errorf("Sent message has group ID %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Attributes["foo"], "bar"; got != want {
+		t.Errorf("Sent message has attribute foo=%q, want %q", got, want)
+	}
+	if got, want := msgs[1].QueueURL, "https://sqs.us-east-1.amazonaws.com/123456789012/otherqueue"; got != want {
+		t.Errorf("Sent message has queue %q, want %q", got, want)
+	}
+
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), pubeesqs.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestDriver_Batching(t *testing.T) {
+	st := newSQSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesqs.NewDriver(st.Client(t), "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue",
+		pubeesqs.WithBatchSize(2),
+		pubeesqs.WithBatchTimeout(time.Hour),
+	)
+
+	var errChs []<-chan error
+	for i := 0; i < 5; i++ {
+		errChs = append(errChs, driver.Publish(ctx, &pubee.Message{Data: []byte(fmt.Sprint(i))}))
+	}
+
+	if got, want := len(st.Batches()), 2; got > want {
+		t.Errorf("Sent batches before Flush() are %d, want at most %d", got, want)
+	}
+
+	driver.Flush()
+
+	for _, errCh := range errChs {
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v", err)
+		}
+	}
+
+	var bodies []string
+	for _, b := range st.Batches() {
+		if got, want := len(b), 2; got > want {
+			t.Errorf("Sent batch has %d messages, want at most %d", got, want)
+		}
+		for _, msg := range b {
+			bodies = append(bodies, msg.Message)
+		}
+	}
+	if got, want := strings.Join(bodies, ","), "0,1,2,3,4"; got != want {
+		t.Errorf("Sent messages are %q, want %q", got, want)
+	}
+
+	driver.Close(ctx)
+}
+
+func TestDriver_WithInvalidBatchSize(t *testing.T) {
+	st := newSQSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesqs.NewDriver(st.Client(t), "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue",
+		pubeesqs.WithBatchSize(0),
+		pubeesqs.WithBatchTimeout(0),
+	)
+
+	errCh := driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	driver.Close(ctx)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := len(st.Batches()), 1; got != want {
+		t.Errorf("Sent batches are %d, want %d", got, want)
+	}
+}
+
+func TestDriver_WhenEntryFailed(t *testing.T) {
+	st := newSQSTest(t)
+	defer st.Close()
+	st.failFunc = func(msg *sentMessage) *resultEntry {
+		switch msg.Message {
+		case "invalid":
+			return &resultEntry{Code: "InvalidParameterValue", Message: "invalid message", SenderFault: true}
+		case "unavailable":
+			return &resultEntry{Code: "ServiceUnavailable", Message: "service unavailable"}
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+
+	driver := pubeesqs.NewDriver(st.Client(t), "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue")
+	defer driver.Close(ctx)
+
+	errCh1 := driver.Publish(ctx, &pubee.Message{Data: []byte("valid")})
+	errCh2 := driver.Publish(ctx, &pubee.Message{Data: []byte("invalid")})
+	errCh3 := driver.Publish(ctx, &pubee.Message{Data: []byte("unavailable")})
+	driver.Flush()
+
+	if err := <-errCh1; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+
+	var entryErr *pubeesqs.EntryError
+	err := <-errCh2
+	if !errors.As(err, &entryErr) {
+		t.Fatalf("Publish() returned %v, want an EntryError", err)
+	}
+	if got, want := entryErr.Code, "InvalidParameterValue"; got != want {
+		t.Errorf("EntryError has code %q, want %q", got, want)
+	}
+	if pubee.IsRetryable(err) {
+		t.Errorf("%v should not be retryable", err)
+	}
+
+	if err := <-errCh3; !pubee.IsRetryable(err) {
+		t.Errorf("%v should be retryable", err)
+	}
+}
+
+func TestDriver_WithBinaryData(t *testing.T) {
+	st := newSQSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesqs.NewDriver(st.Client(t), "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue")
+	defer driver.Close(ctx)
+
+	data := []byte{0x0a, 0x03, 0xff, 0x00}
+	for _, msg := range []*pubee.Message{
+		{Data: data, Metadata: map[string]string{"foo": "bar"}},
+		{Data: []byte("text message")},
+	} {
+		errCh := driver.Publish(ctx, msg)
+		driver.Flush()
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v", err)
+		}
+	}
+
+	var msgs []*sentMessage
+	for _, b := range st.Batches() {
+		msgs = append(msgs, b...)
+	}
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Sent messages are %d, want %d", got, want)
+	}
+	if got, want := msgs[0].Message, base64.StdEncoding.EncodeToString(data); got != want {
+		t.Errorf("Sent message has body %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Attributes[pubeesqs.EncodingKey], "base64"; got != want {
+		t.Errorf("Sent message has attribute %s=%q, want %q", pubeesqs.EncodingKey, got, want)
+	}
+	if got, want := msgs[0].Attributes["foo"], "bar"; got != want {
+		t.Errorf("Sent message has attribute foo=%q, want %q", got, want)
+	}
+	if got, want := msgs[1].Message, "text message"; got != want {
+		t.Errorf("Sent message has body %q, want %q", got, want)
+	}
+	if _, ok := msgs[1].Attributes[pubeesqs.EncodingKey]; ok {
+		t.Errorf("Sent text message should not have attribute %s", pubeesqs.EncodingKey)
+	}
+}
+
+func TestDriver_WhenMessageTooLarge(t *testing.T) {
+	st := newSQSTest(t)
+	defer st.Close()
+
+	ctx := context.Background()
+
+	driver := pubeesqs.NewDriver(st.Client(t), "https://sqs.us-east-1.amazonaws.com/123456789012/awesomequeue")
+	defer driver.Close(ctx)
+
+	err := <-driver.Publish(ctx, &pubee.Message{Data: make([]byte, 256*1024+1)})
+	if !errors.Is(err, pubeesqs.ErrMessageTooLarge) {
+		t.Errorf("Publish() returned %v, want %v", err, pubeesqs.ErrMessageTooLarge)
+	}
+
+	driver.Flush()
+
+	if got, want := len(st.Batches()), 0; got != want {
+		t.Errorf("Sent batches are %d, want %d", got, want)
+	}
+}
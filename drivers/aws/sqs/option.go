@@ -0,0 +1,51 @@
+package sqs
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Config represents driver configuration.
+type Config struct {
+	BatchSize    int
+	BatchTimeout time.Duration
+	LoadOptions  []func(*config.LoadOptions) error
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := new(Config)
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithBatchSize returns an Option that set the maximum number of messages sent at once.
+// It should be 10 or less. Defaults to 10, and non-positive values are ignored.
+func WithBatchSize(n int) Option {
+	return func(c *Config) {
+		c.BatchSize = n
+	}
+}
+
+// WithBatchTimeout returns an Option that set how long messages are buffered before sent. Defaults to 10ms, and non-positive values are ignored.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.BatchTimeout = d
+	}
+}
+
+// WithLoadOptions returns an Option that set options to load the AWS config in CreateDriver.
+func WithLoadOptions(opts ...func(*config.LoadOptions) error) Option {
+	return func(c *Config) {
+		c.LoadOptions = append(c.LoadOptions, opts...)
+	}
+}
@@ -2,20 +2,35 @@ package cloudpubsub
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/izumin5210/pubee"
 )
 
 type Driver struct {
-	client *pubsub.Client
-	topic  *pubsub.Topic
-	cfg    *Config
+	client       *pubsub.Client
+	defaultTopic string
+	cfg          *Config
+
+	mu     sync.Mutex
+	topics map[string]*topicEntry
+}
+
+// topicEntry is a topic being prepared. Its fields are set before done is closed.
+type topicEntry struct {
+	done  chan struct{}
+	topic *pubsub.Topic
+	err   error
 }
 
 var _ pubee.Driver = (*Driver)(nil)
 
+// CreateDriver creates a Driver that publishes messages to topicID by default.
+// Messages with pubee.WithTopic are published to the specified topics, which are prepared lazily with the same options.
+// When topicID is empty, every message should have a topic.
 func CreateDriver(ctx context.Context, projectID, topicID string, opts ...Option) (*Driver, error) {
 	cfg := new(Config)
 	cfg.apply(opts)
@@ -25,14 +40,88 @@ func CreateDriver(ctx context.Context, projectID, topicID string, opts ...Option
 		return nil, err
 	}
 
-	topic := cli.Topic(topicID)
+	d := &Driver{
+		client:       cli,
+		defaultTopic: topicID,
+		cfg:          cfg,
+		topics:       map[string]*topicEntry{},
+	}
+
+	if topicID != "" {
+		_, err = d.getTopic(ctx, topicID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+func (d *Driver) getTopic(ctx context.Context, topicID string) (*pubsub.Topic, error) {
+	if topicID == "" {
+		topicID = d.defaultTopic
+	}
+	if topicID == "" {
+		return nil, errors.New("topic is not specified")
+	}
+
+	// each topic is prepared once without the lock, so that preparing a topic does not block publishing to the others.
+	d.mu.Lock()
+	e, ok := d.topics[topicID]
+	if !ok {
+		e = &topicEntry{done: make(chan struct{})}
+		d.topics[topicID] = e
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		e.topic, e.err = d.prepareTopic(ctx, topicID)
+		if e.err != nil {
+			// failures are not cached, so that the topic can be prepared after it is created.
+			d.mu.Lock()
+			delete(d.topics, topicID)
+			d.mu.Unlock()
+		}
+		close(e.done)
+	}
+
+	select {
+	case <-e.done:
+		return e.topic, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// preparedTopics returns topics that have been prepared successfully.
+func (d *Driver) preparedTopics() []*pubsub.Topic {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	topics := make([]*pubsub.Topic, 0, len(d.topics))
+	for _, e := range d.topics {
+		select {
+		case <-e.done:
+			if e.topic != nil {
+				topics = append(topics, e.topic)
+			}
+		default:
+		}
+	}
+	return topics
+}
+
+func (d *Driver) prepareTopic(ctx context.Context, topicID string) (*pubsub.Topic, error) {
+	cfg := d.cfg
+
+	topic := d.client.Topic(topicID)
 	if ok, err := topic.Exists(ctx); err != nil {
 		return nil, err
 	} else if !ok {
 		if !cfg.CreateTopic {
 			return nil, fmt.Errorf("%s does not exist", topic.ID())
 		}
-		topic, err = cli.CreateTopic(ctx, topicID)
+		topic, err = d.client.CreateTopic(ctx, topicID)
 		if err != nil {
 			return nil, err
 		}
@@ -49,41 +138,82 @@ func CreateDriver(ctx context.Context, projectID, topicID string, opts ...Option
 		f(&topic.PublishSettings)
 	}
 
-	return &Driver{
-		client: cli,
-		topic:  topic,
-		cfg:    cfg,
-	}, nil
+	topic.EnableMessageOrdering = cfg.MessageOrdering
+
+	return topic, nil
 }
 
+// Publish publishes the message in the caller's goroutine and waits for the result asynchronously,
+// so that messages with the same ordering key are published in the order of calls.
 func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
 	errCh := make(chan error, 1)
+
+	topic, err := d.getTopic(ctx, msg.Topic)
+	if err != nil {
+		errCh <- err
+		close(errCh)
+		return errCh
+	}
+
+	res := topic.Publish(ctx, &pubsub.Message{
+		Data:        msg.Data,
+		Attributes:  msg.Metadata,
+		OrderingKey: msg.OrderingKey,
+	})
+
 	go func() {
 		defer close(errCh)
-		psMsg := &pubsub.Message{
-			Data:       msg.Data,
-			Attributes: msg.Metadata,
-		}
-		res := d.topic.Publish(ctx, psMsg)
 
-		_, err := res.Get(context.Background())
+		id, err := res.Get(context.Background())
 		if err != nil {
+			if msg.OrderingKey != "" && d.cfg.ResumePublishOnFail {
+				topic.ResumePublish(msg.OrderingKey)
+			}
 			errCh <- err
+			return
 		}
+		msg.ID = id
 	}()
 	return errCh
 }
 
+// ResumePublish resumes publishing messages with the ordering key to the topic, that was paused by a failure.
+// The default topic is used when topicID is empty.
+func (d *Driver) ResumePublish(topicID, orderingKey string) {
+	if topicID == "" {
+		topicID = d.defaultTopic
+	}
+
+	d.mu.Lock()
+	e, ok := d.topics[topicID]
+	d.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	select {
+	case <-e.done:
+		if e.topic != nil {
+			e.topic.ResumePublish(orderingKey)
+		}
+	default:
+	}
+}
+
 func (d *Driver) Flush() {
-	d.topic.Stop()
+	for _, topic := range d.preparedTopics() {
+		topic.Stop()
+	}
 }
 
 func (d *Driver) Close(ctx context.Context) error {
 	d.Flush()
 	if d.cfg.DeleteTopic {
-		err := d.topic.Delete(ctx)
-		if err != nil {
-			pubee.GetErrorLog(ctx).Printf("failed to delete a topic: %v", err)
+		for _, topic := range d.preparedTopics() {
+			err := topic.Delete(ctx)
+			if err != nil {
+				pubee.GetErrorLog(ctx).Printf("failed to delete a topic: %v", err)
+			}
 		}
 	}
 	err := d.client.Close()
@@ -2,12 +2,15 @@ package cloudpubsub_test
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
 	"google.golang.org/api/option"
+	pubsubpb "google.golang.org/genproto/googleapis/pubsub/v1"
 	"google.golang.org/grpc"
 
 	"github.com/izumin5210/pubee"
@@ -46,7 +49,7 @@ func (pst *pubsubtest) TopicExists(t *testing.T, id string) bool {
 	client := pst.Client(t)
 	defer client.Close()
 
-	ok, err := client.Topic("awesometopic").Exists(context.Background())
+	ok, err := client.Topic(id).Exists(context.Background())
 	if err != nil {
 		t.Fatalf("failed to check topic existence: %v", err)
 	}
@@ -90,17 +93,336 @@ func TestDriver(t *testing.T) {
 		t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
 	}
 
-	err = <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	msg := &pubee.Message{Data: []byte("test message")}
+	err = <-driver.Publish(ctx, msg)
 	if err != nil {
 		t.Errorf("failed to publish a message: %v", err)
 	}
 	driver.Close(ctx)
 
+	if msg.ID == "" {
+		t.Error("Published message should have an ID")
+	}
+
 	if got, want := len(pst.Server.Messages()), 1; got != want {
 		t.Errorf("Received messages are %d, want %d", got, want)
 	}
 }
 
+func TestDriver_WithMultipleTopics(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	driver, err := cloudpubsub.CreateDriver(ctx,
+		"awesomeproj",
+		"awesometopic",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+		cloudpubsub.WithCreateTopicIfNeeded(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
+	}
+
+	for _, msg := range []*pubee.Message{
+		{Data: []byte("test message1")},
+		{Data: []byte("test message2"), Topic: "othertopic"},
+		{Data: []byte("test message3"), Topic: "othertopic"},
+	} {
+		err = <-driver.Publish(ctx, msg)
+		if err != nil {
+			t.Errorf("failed to publish a message: %v", err)
+		}
+	}
+	driver.Close(ctx)
+
+	if got, want := len(pst.Server.Messages()), 3; got != want {
+		t.Errorf("Received messages are %d, want %d", got, want)
+	}
+	if got, want := pst.TopicExists(t, "othertopic"), true; got != want {
+		t.Errorf("Topic.Exists() returned %t, want %t", got, want)
+	}
+}
+
+func TestDriver_WithMultipleTopics_WhenPreparing(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	var (
+		mu        sync.Mutex
+		calls     int
+		preparing = make(chan struct{})
+		release   = make(chan struct{})
+	)
+	conn, err := grpc.Dial(pst.Server.Addr, grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if r, ok := req.(*pubsubpb.GetTopicRequest); ok && r.Topic == "projects/awesomeproj/topics/slowtopic" {
+				mu.Lock()
+				if calls++; calls == 1 {
+					close(preparing)
+				}
+				mu.Unlock()
+				<-release
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect pstest.Server: %v", err)
+	}
+
+	driver, err := cloudpubsub.CreateDriver(ctx,
+		"awesomeproj",
+		"awesometopic",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(conn)),
+		cloudpubsub.WithCreateTopicIfNeeded(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
+	}
+	defer driver.Close(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := <-driver.Publish(ctx, &pubee.Message{Data: []byte("slow message"), Topic: "slowtopic"}); err != nil {
+				t.Errorf("failed to publish a message: %v", err)
+			}
+		}()
+	}
+	<-preparing
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("failed to publish a message: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Publishing to a prepared topic should not wait for preparing another topic")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if got, want := calls, 1; got != want {
+		t.Errorf("Topic is prepared %d times, want %d", got, want)
+	}
+	if got, want := len(pst.Server.Messages()), 4; got != want {
+		t.Errorf("Received messages are %d, want %d", got, want)
+	}
+}
+
+func TestDriver_WithMessageOrdering(t *testing.T) {
+	cases := []struct {
+		test       string
+		opts       []cloudpubsub.Option
+		wantResume bool
+	}{
+		{
+			test: "paused after failure",
+		},
+		{
+			test:       "resumed after failure",
+			opts:       []cloudpubsub.Option{cloudpubsub.WithResumePublishOnFail()},
+			wantResume: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			pst := newPubsubTest(t)
+			defer pst.Close()
+
+			ctx := context.Background()
+
+			driver, err := cloudpubsub.CreateDriver(ctx,
+				"awesomeproj",
+				"awesometopic",
+				append([]cloudpubsub.Option{
+					cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+					cloudpubsub.WithCreateTopicIfNeeded(),
+					cloudpubsub.WithMessageOrdering(),
+				}, tc.opts...)...,
+			)
+			if err != nil {
+				t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
+			}
+			defer driver.Close(ctx)
+
+			err = <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message1"), OrderingKey: "awesomekey"})
+			if err != nil {
+				t.Errorf("failed to publish a message: %v", err)
+			}
+
+			// too large message
+			err = <-driver.Publish(ctx, &pubee.Message{Data: make([]byte, 11*1000*1000), OrderingKey: "awesomekey"})
+			if err == nil {
+				t.Error("Publish() should return an error")
+			}
+
+			err = <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message2"), OrderingKey: "awesomekey"})
+			if got, want := err == nil, tc.wantResume; got != want {
+				t.Errorf("Publish() after a failure returned %v, want success: %t", err, want)
+			}
+
+			driver.ResumePublish("", "awesomekey")
+
+			err = <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message3"), OrderingKey: "awesomekey"})
+			if err != nil {
+				t.Errorf("Publish() after ResumePublish() returned %v, want nil", err)
+			}
+
+			msgs := pst.Server.Messages()
+			for _, msg := range msgs {
+				if got, want := msg.OrderingKey, "awesomekey"; got != want {
+					t.Errorf("Received message has ordering key %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDriver_WithMessageOrdering_Order(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	driver, err := cloudpubsub.CreateDriver(ctx,
+		"awesomeproj",
+		"awesometopic",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+		cloudpubsub.WithCreateTopicIfNeeded(),
+		cloudpubsub.WithMessageOrdering(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
+	}
+
+	const n = 300
+	publisher := pubee.New(driver, pubee.WithOrderingKey("awesomekey"))
+	results := make([]*pubee.PublishResult, n)
+	for i := range results {
+		results[i] = publisher.Publish(ctx, strconv.Itoa(i))
+	}
+	for _, res := range results {
+		if _, err := res.Get(ctx); err != nil {
+			t.Errorf("failed to publish a message: %v", err)
+		}
+	}
+	publisher.Close(ctx)
+
+	msgs := pst.Server.Messages()
+	if got, want := len(msgs), n; got != want {
+		t.Fatalf("Received messages are %d, want %d", got, want)
+	}
+	for i, msg := range msgs {
+		if got, want := string(msg.Data), strconv.Itoa(i); got != want {
+			t.Fatalf("Received message #%d is %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestDriver_Close_Drain(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	driver, err := cloudpubsub.CreateDriver(ctx,
+		"awesomeproj",
+		"awesometopic",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+		cloudpubsub.WithCreateTopicIfNeeded(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
+	}
+
+	const n = 300
+	publisher := pubee.New(driver)
+	results := make([]*pubee.PublishResult, n)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = publisher.Publish(ctx, strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+
+	if err := publisher.Close(ctx); err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+
+	for _, res := range results {
+		if _, err := res.Get(ctx); err != nil {
+			t.Errorf("failed to publish a message: %v", err)
+		}
+	}
+	if got, want := len(pst.Server.Messages()), n; got != want {
+		t.Errorf("Received messages are %d, want %d", got, want)
+	}
+}
+
+func TestDriver_Close_Concurrent(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	driver, err := cloudpubsub.CreateDriver(ctx,
+		"awesomeproj",
+		"awesometopic",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+		cloudpubsub.WithCreateTopicIfNeeded(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create a cloudpubsub.Driver: %v", err)
+	}
+
+	const n = 300
+	publisher := pubee.New(driver)
+	results := make([]*pubee.PublishResult, n)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = publisher.Publish(ctx, strconv.Itoa(i))
+		}(i)
+		if i == n/2 {
+			go publisher.Close(ctx)
+		}
+	}
+	wg.Wait()
+
+	var published int
+	for _, res := range results {
+		switch _, err := res.Get(ctx); err {
+		case nil:
+			published++
+		case pubee.ErrClosed:
+		default:
+			t.Errorf("failed to publish a message: %v", err)
+		}
+	}
+	if got, want := len(pst.Server.Messages()), published; got != want {
+		t.Errorf("Received messages are %d, want %d", got, want)
+	}
+}
+
 func TestDriver_WithoutTopic(t *testing.T) {
 	pst := newPubsubTest(t)
 	defer pst.Close()
@@ -12,6 +12,12 @@ type Config struct {
 	TopicConfig         *pubsub.TopicConfigToUpdate
 	CreateTopic         bool
 	DeleteTopic         bool
+	MessageOrdering     bool
+	ResumePublishOnFail bool
+
+	ReceiveSettingsFunc func(*pubsub.ReceiveSettings)
+	SubscriptionTopicID string
+	DeleteSubscription  bool
 }
 
 func (c *Config) apply(opts []Option) {
@@ -55,3 +61,39 @@ func WithDeleteTopicOnClose() Option {
 		c.DeleteTopic = true
 	}
 }
+
+// WithMessageOrdering returns an Option that enables message ordering on topics and created subscriptions.
+// It is required to publish messages with pubee.WithOrderingKey.
+func WithMessageOrdering() Option {
+	return func(c *Config) {
+		c.MessageOrdering = true
+	}
+}
+
+// WithResumePublishOnFail returns an Option that resumes publishing for an ordering key soon after a publish with the key failed.
+// Otherwise publishing for the key is paused until Driver.ResumePublish is called, to prevent messages from being published out of order.
+func WithResumePublishOnFail() Option {
+	return func(c *Config) {
+		c.ResumePublishOnFail = true
+	}
+}
+
+// WithReceiveSettings returns an Option that set pubsub.ReceiveSettings to the pubsub.Subscription.
+func WithReceiveSettings(f func(*pubsub.ReceiveSettings)) Option {
+	return func(c *Config) {
+		c.ReceiveSettingsFunc = f
+	}
+}
+
+// WithCreateSubscriptionIfNeeded returns an Option that creates a subscription for the given topic when it does not exist.
+func WithCreateSubscriptionIfNeeded(topicID string) Option {
+	return func(c *Config) {
+		c.SubscriptionTopicID = topicID
+	}
+}
+
+func WithDeleteSubscriptionOnClose() Option {
+	return func(c *Config) {
+		c.DeleteSubscription = true
+	}
+}
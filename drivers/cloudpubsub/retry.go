@@ -0,0 +1,28 @@
+package cloudpubsub
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/izumin5210/pubee"
+)
+
+var _ pubee.RetryClassifier = (*Driver)(nil)
+
+// IsRetryable reports whether the error returned from Cloud Pub/Sub is temporary, based on its gRPC status code.
+func IsRetryable(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return pubee.IsRetryable(err)
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *Driver) IsRetryable(err error) bool {
+	return IsRetryable(err)
+}
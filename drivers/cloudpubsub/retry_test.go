@@ -0,0 +1,35 @@
+package cloudpubsub_test
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/cloudpubsub"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: status.Error(codes.Unavailable, "unavailable"), want: true},
+		{err: status.Error(codes.DeadlineExceeded, "deadline exceeded"), want: true},
+		{err: status.Error(codes.ResourceExhausted, "resource exhausted"), want: true},
+		{err: status.Error(codes.InvalidArgument, "invalid argument"), want: false},
+		{err: status.Error(codes.NotFound, "not found"), want: false},
+		{err: status.Error(codes.PermissionDenied, "permission denied"), want: false},
+		{err: errors.New("unknown error"), want: false},
+		{err: pubee.Retryable(errors.New("unknown error")), want: true},
+	}
+
+	for _, tc := range cases {
+		if got, want := cloudpubsub.IsRetryable(tc.err), tc.want; got != want {
+			t.Errorf("IsRetryable(%v) returned %t, want %t", tc.err, got, want)
+		}
+	}
+}
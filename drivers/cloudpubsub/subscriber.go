@@ -0,0 +1,77 @@
+package cloudpubsub
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/izumin5210/pubee"
+)
+
+type SubscriberDriver struct {
+	client *pubsub.Client
+	sub    *pubsub.Subscription
+	cfg    *Config
+}
+
+var _ pubee.SubscriberDriver = (*SubscriberDriver)(nil)
+
+func CreateSubscriberDriver(ctx context.Context, projectID, subscriptionID string, opts ...Option) (*SubscriberDriver, error) {
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	cli, err := pubsub.NewClient(ctx, projectID, cfg.ClientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := cli.Subscription(subscriptionID)
+	if ok, err := sub.Exists(ctx); err != nil {
+		return nil, err
+	} else if !ok {
+		if cfg.SubscriptionTopicID == "" {
+			return nil, fmt.Errorf("%s does not exist", sub.ID())
+		}
+		sub, err = cli.CreateSubscription(ctx, subscriptionID, pubsub.SubscriptionConfig{
+			Topic:                 cli.Topic(cfg.SubscriptionTopicID),
+			EnableMessageOrdering: cfg.MessageOrdering,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f := cfg.ReceiveSettingsFunc; f != nil {
+		f(&sub.ReceiveSettings)
+	}
+
+	return &SubscriberDriver{
+		client: cli,
+		sub:    sub,
+		cfg:    cfg,
+	}, nil
+}
+
+func (d *SubscriberDriver) Receive(ctx context.Context, f func(context.Context, *pubee.ReceivedMessage)) error {
+	return d.sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		f(ctx, &pubee.ReceivedMessage{
+			ID:          m.ID,
+			OrderingKey: m.OrderingKey,
+			Data:        m.Data,
+			Metadata:    m.Attributes,
+			PublishTime: m.PublishTime,
+			Acker:       m,
+		})
+	})
+}
+
+func (d *SubscriberDriver) Close(ctx context.Context) error {
+	if d.cfg.DeleteSubscription {
+		err := d.sub.Delete(ctx)
+		if err != nil {
+			pubee.GetErrorLog(ctx).Printf("failed to delete a subscription: %v", err)
+		}
+	}
+	err := d.client.Close()
+	return err
+}
@@ -0,0 +1,83 @@
+package cloudpubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/cloudpubsub"
+)
+
+func TestSubscriberDriver(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	_, err := pst.Client(t).CreateTopic(ctx, "awesometopic")
+	if err != nil {
+		t.Fatalf("failed to create pubsub.Topic: %v", err)
+	}
+
+	driver, err := cloudpubsub.CreateSubscriberDriver(ctx,
+		"awesomeproj",
+		"awesomesub",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+		cloudpubsub.WithCreateSubscriptionIfNeeded("awesometopic"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create a cloudpubsub.SubscriberDriver: %v", err)
+	}
+
+	id := pst.Server.Publish("projects/awesomeproj/topics/awesometopic", []byte("test message"), map[string]string{"foo": "bar"})
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var received *pubee.ReceivedMessage
+	err = driver.Receive(ctx, func(_ context.Context, msg *pubee.ReceivedMessage) {
+		received = msg
+		msg.Ack()
+		cancel()
+	})
+	if err != nil {
+		t.Errorf("Receive() returned %v, want nil", err)
+	}
+	driver.Close(context.Background())
+
+	if received == nil {
+		t.Fatal("message was not received")
+	}
+	if got, want := received.ID, id; got != want {
+		t.Errorf("Received message has ID %q, want %q", got, want)
+	}
+	if got, want := string(received.Data), "test message"; got != want {
+		t.Errorf("Received message has data %q, want %q", got, want)
+	}
+	if got, want := received.Metadata["foo"], "bar"; got != want {
+		t.Errorf("Received message has metadata foo=%q, want %q", got, want)
+	}
+
+	if got, want := pst.Server.Message(id).Acks, 1; got != want {
+		t.Errorf("Message is acked %d times, want %d", got, want)
+	}
+}
+
+func TestSubscriberDriver_WithoutSubscription(t *testing.T) {
+	pst := newPubsubTest(t)
+	defer pst.Close()
+
+	ctx := context.Background()
+
+	_, err := cloudpubsub.CreateSubscriberDriver(ctx,
+		"awesomeproj",
+		"awesomesub",
+		cloudpubsub.WithClientOptions(option.WithGRPCConn(pst.Conn(t))),
+	)
+	if err == nil {
+		t.Error("CreateSubscriberDriver should return an error")
+	}
+}
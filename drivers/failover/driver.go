@@ -0,0 +1,209 @@
+// Package failover provides a pubee.Driver that fails over to fallback backends when the primary one keeps failing.
+//
+// Messages are published to the active backend. After consecutive failures reach the threshold,
+// the next backend becomes active. While failed over, a message is published to the primary backend
+// every probe interval, and the primary becomes active again when it succeeds.
+// A failed message is re-published to the active backend, and then to the following backends in order,
+// until one of them succeeds. Each backend is tried at most once per message.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Backend is a named driver.
+type Backend struct {
+	Name   string
+	Driver pubee.Driver
+}
+
+type Driver struct {
+	backends []Backend
+	cfg      *Config
+
+	mu        sync.Mutex
+	active    int
+	failures  []int
+	lastProbe time.Time
+}
+
+var (
+	_ pubee.Driver          = (*Driver)(nil)
+	_ pubee.RetryClassifier = (*Driver)(nil)
+)
+
+// NewDriver creates a Driver. The first backend is the primary one, and the others are fallbacks in order.
+func NewDriver(backends []Backend, opts ...Option) (*Driver, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("failover: no backends are specified")
+	}
+	for i, b := range backends {
+		if b.Driver == nil {
+			return nil, fmt.Errorf("failover: backend #%d (%q) has no driver", i, b.Name)
+		}
+	}
+
+	return &Driver{
+		backends: backends,
+		cfg:      newConfig(opts),
+		failures: make([]int, len(backends)),
+	}, nil
+}
+
+// Active returns the name of the active backend.
+func (d *Driver) Active() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.backends[d.active].Name
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	i := d.pick()
+	tried := make([]bool, len(d.backends))
+
+	errCh := make(chan error, 1)
+	resCh := d.publish(ctx, i, msg)
+
+	go func() {
+		defer close(errCh)
+
+		for {
+			tried[i] = true
+
+			var err error
+			if resCh != nil {
+				err = <-resCh
+			}
+			d.record(i, err)
+			if err == nil {
+				return
+			}
+
+			next, ok := d.next(tried)
+			if !ok {
+				errCh <- err
+				return
+			}
+			i = next
+			resCh = d.publish(ctx, i, msg)
+		}
+	}()
+
+	return errCh
+}
+
+// publish publishes the message to the i-th backend.
+func (d *Driver) publish(ctx context.Context, i int, msg *pubee.Message) <-chan error {
+	b := d.backends[i]
+
+	if key := d.cfg.MetadataKey; key != "" {
+		md := make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			md[k] = v
+		}
+		md[key] = b.Name
+		msg.Metadata = md
+	}
+
+	return b.Driver.Publish(ctx, msg)
+}
+
+// next returns the index of the backend a failed message is re-published to.
+// The active backend is preferred, and then the backends following it.
+func (d *Driver) next(tried []bool) (int, bool) {
+	d.mu.Lock()
+	active := d.active
+	d.mu.Unlock()
+
+	if !tried[active] {
+		return active, true
+	}
+	for i := range d.backends {
+		if !tried[i] && i > active {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// IsRetryable reports whether err is retryable with the RetryClassifier of any backend, or with pubee.IsRetryable.
+// err is the error from the last backend a message is published to, after all backends failed.
+func (d *Driver) IsRetryable(err error) bool {
+	for _, b := range d.backends {
+		if c, ok := b.Driver.(pubee.RetryClassifier); ok && c.IsRetryable(err) {
+			return true
+		}
+	}
+	return pubee.IsRetryable(err)
+}
+
+// pick returns the index of the backend a message is published to.
+func (d *Driver) pick() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.active != 0 && time.Since(d.lastProbe) >= d.cfg.ProbeInterval {
+		d.lastProbe = time.Now()
+		return 0
+	}
+	return d.active
+}
+
+// record updates the health of the i-th backend and switches the active backend.
+func (d *Driver) record(i int, err error) {
+	d.mu.Lock()
+
+	from := d.active
+	if err == nil {
+		d.failures[i] = 0
+		if i < d.active {
+			d.active = i
+		}
+	} else {
+		d.failures[i]++
+		if i == d.active && d.failures[i] >= d.cfg.Threshold && i+1 < len(d.backends) {
+			d.active = i + 1
+			d.failures[d.active] = 0
+			d.lastProbe = time.Now()
+		}
+	}
+	to := d.active
+
+	d.mu.Unlock()
+
+	if from != to {
+		if f := d.cfg.OnSwitch; f != nil {
+			f(d.backends[from].Name, d.backends[to].Name)
+		}
+	}
+}
+
+// Flush flushes all backends concurrently.
+func (d *Driver) Flush() {
+	var wg sync.WaitGroup
+	for _, b := range d.backends {
+		wg.Add(1)
+		go func(drv pubee.Driver) {
+			defer wg.Done()
+			drv.Flush()
+		}(b.Driver)
+	}
+	wg.Wait()
+}
+
+// Close closes all backends, and returns the first error.
+func (d *Driver) Close(ctx context.Context) error {
+	var firstErr error
+	for _, b := range d.backends {
+		if err := b.Driver.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,176 @@
+package failover_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/failover"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+type outage struct {
+	mu   sync.Mutex
+	down bool
+}
+
+func (o *outage) Set(down bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.down = down
+}
+
+func (o *outage) Inject(d *memory.Driver) *memory.Driver {
+	d.InjectFailure(func(*pubee.Message) error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.down {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	return d
+}
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+
+	var primaryOutage outage
+	primary, standby := primaryOutage.Inject(memory.NewDriver()), memory.NewDriver()
+
+	var switches []string
+	driver, err := failover.NewDriver(
+		[]failover.Backend{
+			{Name: "primary", Driver: primary},
+			{Name: "standby", Driver: standby},
+		},
+		failover.WithThreshold(2),
+		failover.WithProbeInterval(20*time.Millisecond),
+		failover.WithOnSwitch(func(from, to string) {
+			switches = append(switches, from+"->"+to)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewDriver() returned %v", err)
+	}
+	defer driver.Close(ctx)
+
+	publish := func() error {
+		return <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	}
+
+	if err := publish(); err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := primary.Messages()[0].Metadata[failover.DefaultMetadataKey], "primary"; got != want {
+		t.Errorf("Published message has backend %q, want %q", got, want)
+	}
+
+	primaryOutage.Set(true)
+
+	// messages failed on the primary are re-published to the standby
+	for i := 0; i < 2; i++ {
+		if err := publish(); err != nil {
+			t.Errorf("Publish() returned %v during the outage, want nil", err)
+		}
+	}
+	if got, want := driver.Active(), "standby"; got != want {
+		t.Errorf("Active() returned %q, want %q", got, want)
+	}
+
+	if err := publish(); err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := len(standby.Messages()), 3; got != want {
+		t.Fatalf("Standby has %d messages, want %d", got, want)
+	}
+	if got, want := standby.Messages()[0].Metadata[failover.DefaultMetadataKey], "standby"; got != want {
+		t.Errorf("Published message has backend %q, want %q", got, want)
+	}
+
+	// a probe to the primary during the outage is re-published to the standby, and keeps the standby active
+	time.Sleep(20 * time.Millisecond)
+	if err := publish(); err != nil {
+		t.Errorf("Publish() returned %v for a probe during the outage, want nil", err)
+	}
+	if got, want := len(standby.Messages()), 4; got != want {
+		t.Errorf("Standby has %d messages, want %d", got, want)
+	}
+	if got, want := driver.Active(), "standby"; got != want {
+		t.Errorf("Active() returned %q, want %q", got, want)
+	}
+
+	primaryOutage.Set(false)
+
+	// a probe succeeds and fails back to the primary
+	time.Sleep(20 * time.Millisecond)
+	if err := publish(); err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := driver.Active(), "primary"; got != want {
+		t.Errorf("Active() returned %q, want %q", got, want)
+	}
+
+	if got, want := len(switches), 2; got != want {
+		t.Fatalf("Switched %d times, want %d", got, want)
+	}
+	if got, want := switches[0], "primary->standby"; got != want {
+		t.Errorf("Switched %s, want %s", got, want)
+	}
+	if got, want := switches[1], "standby->primary"; got != want {
+		t.Errorf("Switched %s, want %s", got, want)
+	}
+}
+
+func TestDriver_WhenAllFailed(t *testing.T) {
+	ctx := context.Background()
+
+	var outage outage
+	outage.Set(true)
+	primary, standby := outage.Inject(memory.NewDriver()), classifyingDriver{outage.Inject(memory.NewDriver())}
+
+	var attempts int
+	driver, err := failover.NewDriver([]failover.Backend{
+		{Name: "primary", Driver: primary},
+		{Name: "standby", Driver: standby},
+	})
+	if err != nil {
+		t.Fatalf("NewDriver() returned %v", err)
+	}
+	publisher := pubee.New(driver,
+		pubee.WithRetry(pubee.RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		pubee.WithDeadLetterSink(pubee.DeadLetterSinkFunc(func(ctx context.Context, dl *pubee.DeadLetter) error {
+			attempts = dl.Attempts
+			return nil
+		})),
+	)
+
+	if _, err := publisher.Publish(ctx, "test message").Get(ctx); err == nil {
+		t.Error("Publish() should return an error when all backends fail")
+	}
+	publisher.Close(ctx)
+
+	// the error is retryable by the standby's classifier
+	if got, want := attempts, 2; got != want {
+		t.Errorf("Publish is attempted %d times, want %d", got, want)
+	}
+}
+
+// classifyingDriver treats errors as retryable.
+type classifyingDriver struct {
+	*memory.Driver
+}
+
+func (classifyingDriver) IsRetryable(err error) bool { return err != nil }
+
+func TestNewDriver_WithoutBackends(t *testing.T) {
+	if _, err := failover.NewDriver(nil); err == nil {
+		t.Error("NewDriver() should return an error without backends")
+	}
+	if _, err := failover.NewDriver([]failover.Backend{{Name: "primary"}}); err == nil {
+		t.Error("NewDriver() should return an error for a backend without a driver")
+	}
+}
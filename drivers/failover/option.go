@@ -0,0 +1,61 @@
+package failover
+
+import "time"
+
+// DefaultMetadataKey is the metadata key the name of the backend a message is published to is set to.
+const DefaultMetadataKey = "failover-backend"
+
+// Config represents driver configuration.
+type Config struct {
+	Threshold     int
+	ProbeInterval time.Duration
+	MetadataKey   string
+	OnSwitch      func(from, to string)
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		Threshold:     3,
+		ProbeInterval: 30 * time.Second,
+		MetadataKey:   DefaultMetadataKey,
+	}
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithThreshold returns an Option that set the number of consecutive failures to switch to the next backend.
+func WithThreshold(n int) Option {
+	return func(c *Config) {
+		c.Threshold = n
+	}
+}
+
+// WithProbeInterval returns an Option that set how often a message is published to the primary backend while failed over.
+func WithProbeInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.ProbeInterval = d
+	}
+}
+
+// WithMetadataKey returns an Option that set the metadata key the backend name is set to. Empty disables it.
+func WithMetadataKey(key string) Option {
+	return func(c *Config) {
+		c.MetadataKey = key
+	}
+}
+
+// WithOnSwitch returns an Option that set a function called when the active backend is switched.
+func WithOnSwitch(f func(from, to string)) Option {
+	return func(c *Config) {
+		c.OnSwitch = f
+	}
+}
@@ -0,0 +1,253 @@
+// Package fanout provides a pubee.Driver that publishes each message to several drivers, e.g. to dual-publish during a migration.
+//
+// Each driver receives its own copy of a message. The message ID is taken from the first driver,
+// or from the first succeeded driver with RequireAny.
+// Note that retrying a failed message publishes it again to drivers that have succeeded.
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Error holds errors from wrapped drivers.
+type Error struct {
+	// Errs[i] is the error from the i-th driver, and nil when it succeeded.
+	Errs []error
+}
+
+func (e *Error) Error() string {
+	var msgs []string
+	for i, err := range e.Errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("driver #%d: %v", i, err))
+		}
+	}
+	return "fanout: " + strings.Join(msgs, ", ")
+}
+
+// Retryable reports whether all errors are retryable with pubee.IsRetryable.
+// Driver.IsRetryable also respects the RetryClassifier of each driver.
+func (e *Error) Retryable() bool {
+	for _, err := range e.Errs {
+		if err != nil && !pubee.IsRetryable(err) {
+			return false
+		}
+	}
+	return true
+}
+
+type Driver struct {
+	drivers []pubee.Driver
+	cfg     *Config
+	wg      sync.WaitGroup
+}
+
+var (
+	_ pubee.Driver          = (*Driver)(nil)
+	_ pubee.RetryClassifier = (*Driver)(nil)
+)
+
+// NewDriver creates a Driver that publishes messages to all drivers.
+// The first driver is the primary one for RequirePrimary.
+func NewDriver(drivers []pubee.Driver, opts ...Option) (*Driver, error) {
+	if len(drivers) == 0 {
+		return nil, errors.New("fanout: no drivers are specified")
+	}
+	for i, drv := range drivers {
+		if drv == nil {
+			return nil, fmt.Errorf("fanout: driver #%d is nil", i)
+		}
+	}
+
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	return &Driver{
+		drivers: drivers,
+		cfg:     cfg,
+	}, nil
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	msgs := make([]*pubee.Message, len(d.drivers))
+	errChs := make([]<-chan error, len(d.drivers))
+	for i, drv := range d.drivers {
+		msgs[i] = copyMessage(msg)
+		if i > 0 && d.cfg.Policy == RequirePrimary {
+			// secondary drivers keep publishing after the result is reported and ctx is canceled.
+			errChs[i] = drv.Publish(detachedContext{ctx}, msgs[i])
+		} else {
+			errChs[i] = drv.Publish(ctx, msgs[i])
+		}
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		if d.cfg.Policy == RequirePrimary {
+			d.waitPrimary(ctx, msg, msgs, errChs, errCh)
+			return
+		}
+
+		defer close(errCh)
+
+		errs := make([]error, len(errChs))
+		failed := 0
+		for i, ch := range errChs {
+			if errs[i] = wait(ch); errs[i] != nil {
+				failed++
+			}
+		}
+
+		for i, err := range errs {
+			if err == nil {
+				msg.ID = msgs[i].ID
+				break
+			}
+		}
+
+		switch {
+		case failed == 0:
+		case d.cfg.Policy == RequireAny && failed < len(errs):
+		default:
+			errCh <- &Error{Errs: errs}
+		}
+	}()
+
+	return errCh
+}
+
+func (d *Driver) waitPrimary(ctx context.Context, msg *pubee.Message, msgs []*pubee.Message, errChs []<-chan error, errCh chan<- error) {
+	if err := wait(errChs[0]); err != nil {
+		errCh <- err
+	} else {
+		msg.ID = msgs[0].ID
+	}
+	close(errCh)
+
+	for i := 1; i < len(errChs); i++ {
+		if err := wait(errChs[i]); err != nil {
+			if f := d.cfg.OnSecondaryFail; f != nil {
+				f(i, msgs[i], err)
+			} else {
+				pubee.GetErrorLog(ctx).Printf("failed to publish message to secondary driver #%d: %v (metadata: %v)", i, err, msgs[i].Metadata)
+			}
+		}
+	}
+}
+
+// IsRetryable reports whether err is retryable.
+// Each error in an *Error is classified by the RetryClassifier of the driver returned it, or by pubee.IsRetryable.
+func (d *Driver) IsRetryable(err error) bool {
+	fe, ok := err.(*Error)
+	if !ok {
+		// errors from the primary driver are returned as is with RequirePrimary.
+		if d.cfg.Policy == RequirePrimary && len(d.drivers) > 0 {
+			return d.isRetryable(0, err)
+		}
+		return pubee.IsRetryable(err)
+	}
+
+	for i, err := range fe.Errs {
+		if err != nil && !d.isRetryable(i, err) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Driver) isRetryable(i int, err error) bool {
+	if i < len(d.drivers) {
+		if c, ok := d.drivers[i].(pubee.RetryClassifier); ok {
+			return c.IsRetryable(err)
+		}
+	}
+	return pubee.IsRetryable(err)
+}
+
+// Flush flushes all drivers concurrently, and waits for results of all messages including best-effort ones.
+func (d *Driver) Flush() {
+	d.flush()
+	d.wg.Wait()
+}
+
+func (d *Driver) flush() {
+	var wg sync.WaitGroup
+	for _, drv := range d.drivers {
+		wg.Add(1)
+		go func(drv pubee.Driver) {
+			defer wg.Done()
+			drv.Flush()
+		}(drv)
+	}
+	wg.Wait()
+}
+
+// Close flushes and closes all drivers concurrently, and returns an *Error if some of them failed.
+func (d *Driver) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(d.drivers))
+	for i, drv := range d.drivers {
+		wg.Add(1)
+		go func(i int, drv pubee.Driver) {
+			defer wg.Done()
+			errs[i] = drv.Close(ctx)
+		}(i, drv)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return &Error{Errs: errs}
+		}
+	}
+	return nil
+}
+
+// wait returns the first error from ch. A nil channel means the message was dropped without errors.
+func wait(ch <-chan error) error {
+	if ch == nil {
+		return nil
+	}
+	return <-ch
+}
+
+func copyMessage(msg *pubee.Message) *pubee.Message {
+	copied := *msg
+	if msg.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(msg.Metadata))
+		for k, v := range msg.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return &copied
+}
+
+// detachedContext has the values of the parent, but is never canceled.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
@@ -0,0 +1,204 @@
+package fanout_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/fanout"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+func failingDriver() *memory.Driver {
+	d := memory.NewDriver()
+	d.InjectFailure(func(*pubee.Message) error { return errors.New("unavailable") })
+	return d
+}
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+	d1, d2 := memory.NewDriver(), memory.NewDriver()
+	driver, err := fanout.NewDriver([]pubee.Driver{d1, d2})
+	if err != nil {
+		t.Fatalf("NewDriver() returned %v", err)
+	}
+	publisher := pubee.New(driver, pubee.WithMetadata("foo", "bar"))
+
+	id, err := publisher.Publish(ctx, "test message").Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := id, d1.Messages()[0].ID; got != want {
+		t.Errorf("Published message has ID %q, want %q", got, want)
+	}
+
+	if err := publisher.Close(ctx); err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+
+	for i, d := range []*memory.Driver{d1, d2} {
+		msgs := d.Messages()
+		if got, want := len(msgs), 1; got != want {
+			t.Fatalf("Driver #%d has %d messages, want %d", i, got, want)
+		}
+		if got, want := string(msgs[0].Data), "test message"; got != want {
+			t.Errorf("Driver #%d has message %q, want %q", i, got, want)
+		}
+		if got, want := msgs[0].Metadata["foo"], "bar"; got != want {
+			t.Errorf("Driver #%d has metadata foo=%q, want %q", i, got, want)
+		}
+		if got, want := <-d.Publish(ctx, &pubee.Message{}), memory.ErrClosed; got != want {
+			t.Errorf("Driver #%d should be closed", i)
+		}
+	}
+}
+
+func TestDriver_Policy(t *testing.T) {
+	cases := []struct {
+		test     string
+		policy   fanout.Policy
+		failures []bool
+		wantErr  bool
+	}{
+		{test: "all: all succeeded", policy: fanout.RequireAll, failures: []bool{false, false}},
+		{test: "all: one failed", policy: fanout.RequireAll, failures: []bool{false, true}, wantErr: true},
+		{test: "any: one failed", policy: fanout.RequireAny, failures: []bool{true, false}},
+		{test: "any: all failed", policy: fanout.RequireAny, failures: []bool{true, true}, wantErr: true},
+		{test: "primary: secondary failed", policy: fanout.RequirePrimary, failures: []bool{false, true}},
+		{test: "primary: primary failed", policy: fanout.RequirePrimary, failures: []bool{true, false}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			ctx := context.Background()
+
+			var drivers []pubee.Driver
+			for _, failure := range tc.failures {
+				if failure {
+					drivers = append(drivers, failingDriver())
+				} else {
+					drivers = append(drivers, memory.NewDriver())
+				}
+			}
+
+			var secondaryErrs []int
+			driver, err := fanout.NewDriver(drivers,
+				fanout.WithPolicy(tc.policy),
+				fanout.WithOnSecondaryFail(func(i int, msg *pubee.Message, err error) {
+					secondaryErrs = append(secondaryErrs, i)
+				}),
+			)
+			if err != nil {
+				t.Fatalf("NewDriver() returned %v", err)
+			}
+
+			err = <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("Publish() returned %v, want error: %t", err, want)
+			}
+
+			driver.Close(ctx)
+
+			if tc.policy == fanout.RequirePrimary && tc.failures[1] {
+				if got, want := len(secondaryErrs), 1; got != want {
+					t.Errorf("OnSecondaryFail is called %d times, want %d", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDriver_Error(t *testing.T) {
+	ctx := context.Background()
+
+	d2 := memory.NewDriver()
+	d2.InjectFailure(func(*pubee.Message) error { return pubee.Retryable(errors.New("unavailable")) })
+	driver, err := fanout.NewDriver([]pubee.Driver{memory.NewDriver(), d2})
+	if err != nil {
+		t.Fatalf("NewDriver() returned %v", err)
+	}
+	defer driver.Close(ctx)
+
+	err = <-driver.Publish(ctx, &pubee.Message{})
+
+	var fanoutErr *fanout.Error
+	if !errors.As(err, &fanoutErr) {
+		t.Fatalf("Publish() returned %v, want a fanout.Error", err)
+	}
+	if fanoutErr.Errs[0] != nil {
+		t.Errorf("Error from driver #0 is %v, want nil", fanoutErr.Errs[0])
+	}
+	if fanoutErr.Errs[1] == nil {
+		t.Error("Error from driver #1 should not be nil")
+	}
+	if !pubee.IsRetryable(err) {
+		t.Errorf("%v should be retryable", err)
+	}
+}
+
+// classifyingDriver treats errors of the wrapped driver as retryable.
+type classifyingDriver struct {
+	*memory.Driver
+}
+
+func (classifyingDriver) IsRetryable(err error) bool { return err != nil }
+
+func TestDriver_IsRetryable(t *testing.T) {
+	cases := []struct {
+		test    string
+		policy  fanout.Policy
+		drivers func() []pubee.Driver
+		want    bool
+	}{
+		{
+			test:    "classified by the driver",
+			drivers: func() []pubee.Driver { return []pubee.Driver{memory.NewDriver(), classifyingDriver{failingDriver()}} },
+			want:    true,
+		},
+		{
+			test:    "not classified",
+			drivers: func() []pubee.Driver { return []pubee.Driver{memory.NewDriver(), failingDriver()} },
+			want:    false,
+		},
+		{
+			test:    "partially classified",
+			drivers: func() []pubee.Driver { return []pubee.Driver{failingDriver(), classifyingDriver{failingDriver()}} },
+			want:    false,
+		},
+		{
+			test:    "primary classified",
+			policy:  fanout.RequirePrimary,
+			drivers: func() []pubee.Driver { return []pubee.Driver{classifyingDriver{failingDriver()}, memory.NewDriver()} },
+			want:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			ctx := context.Background()
+			driver, err := fanout.NewDriver(tc.drivers(), fanout.WithPolicy(tc.policy))
+			if err != nil {
+				t.Fatalf("NewDriver() returned %v", err)
+			}
+			defer driver.Close(ctx)
+
+			err = <-driver.Publish(ctx, &pubee.Message{})
+			if err == nil {
+				t.Fatal("Publish() should return an error")
+			}
+			if got, want := driver.IsRetryable(err), tc.want; got != want {
+				t.Errorf("IsRetryable(%v) returned %t, want %t", err, got, want)
+			}
+		})
+	}
+}
+
+func TestNewDriver_WithoutDrivers(t *testing.T) {
+	if _, err := fanout.NewDriver(nil); err == nil {
+		t.Error("NewDriver() should return an error without drivers")
+	}
+	if _, err := fanout.NewDriver([]pubee.Driver{memory.NewDriver(), nil}); err == nil {
+		t.Error("NewDriver() should return an error for a nil driver")
+	}
+}
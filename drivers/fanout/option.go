@@ -0,0 +1,46 @@
+package fanout
+
+import "github.com/izumin5210/pubee"
+
+// Policy decides whether publishing a message to wrapped drivers succeeds.
+type Policy int
+
+const (
+	// RequireAll succeeds when all drivers succeed.
+	RequireAll Policy = iota
+	// RequireAny succeeds when at least one driver succeeds.
+	RequireAny
+	// RequirePrimary succeeds when the first driver succeeds. The others are best-effort,
+	// and their results are not waited for.
+	RequirePrimary
+)
+
+// Config represents driver configuration.
+type Config struct {
+	Policy          Policy
+	OnSecondaryFail func(i int, msg *pubee.Message, err error)
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithPolicy returns an Option that set a Policy. Defaults to RequireAll.
+func WithPolicy(p Policy) Option {
+	return func(c *Config) {
+		c.Policy = p
+	}
+}
+
+// WithOnSecondaryFail returns an Option that set a function called when the i-th driver fails with RequirePrimary.
+// Failures are logged by default.
+func WithOnSecondaryFail(f func(i int, msg *pubee.Message, err error)) Option {
+	return func(c *Config) {
+		c.OnSecondaryFail = f
+	}
+}
@@ -0,0 +1,152 @@
+// Package kafka provides a pubee.Driver for Apache Kafka.
+//
+// Message metadata are mapped to record headers, and ordering keys are mapped to record keys,
+// so that messages with the same ordering key are written to the same partition.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/internal/batch"
+)
+
+// ErrClosed is returned when publishing messages to a closed Driver.
+var ErrClosed = errors.New("kafka: driver is closed")
+
+// Writer writes records to Kafka. *kafka.Writer implements it.
+type Writer interface {
+	WriteMessages(context.Context, ...kafka.Message) error
+	Close() error
+}
+
+type record struct {
+	msg   kafka.Message
+	errCh chan error
+}
+
+type Driver struct {
+	w            Writer
+	defaultTopic string
+	cfg          *Config
+	b            *batch.Batcher
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+// CreateDriver creates a Driver that writes records to the brokers.
+// Records are written to topic by default, and to topics specified by pubee.WithTopic.
+func CreateDriver(ctx context.Context, brokers []string, topic string, opts ...Option) (*Driver, error) {
+	cfg := newConfig(opts)
+
+	if len(brokers) == 0 {
+		return nil, errors.New("kafka: no brokers are specified")
+	}
+	conn, err := kafka.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+	}
+	if f := cfg.WriterFunc; f != nil {
+		f(w)
+	}
+
+	return NewDriver(w, topic, opts...), nil
+}
+
+// NewDriver creates a Driver that writes records with the Writer.
+func NewDriver(w Writer, topic string, opts ...Option) *Driver {
+	d := &Driver{
+		w:            w,
+		defaultTopic: topic,
+		cfg:          newConfig(opts),
+	}
+	d.b = batch.New(d.writeBatch, batch.Config{
+		MaxEntries: d.cfg.BatchSize,
+		Timeout:    d.cfg.BatchTimeout,
+	})
+
+	return d
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	topic := msg.Topic
+	if topic == "" {
+		topic = d.defaultTopic
+	}
+	if topic == "" {
+		errCh <- errors.New("topic is not specified")
+		close(errCh)
+		return errCh
+	}
+
+	r := &record{
+		msg: kafka.Message{
+			Topic: topic,
+			Value: msg.Data,
+		},
+		errCh: errCh,
+	}
+	if msg.OrderingKey != "" {
+		r.msg.Key = []byte(msg.OrderingKey)
+	}
+	for k, v := range msg.Metadata {
+		r.msg.Headers = append(r.msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	if !d.b.Add(&batch.Entry{Value: r}) {
+		errCh <- ErrClosed
+		close(errCh)
+	}
+
+	return errCh
+}
+
+// Flush writes all buffered records and waits for their results.
+func (d *Driver) Flush() {
+	d.b.Flush()
+}
+
+func (d *Driver) Close(ctx context.Context) error {
+	if !d.b.Close() {
+		return nil
+	}
+	return d.w.Close()
+}
+
+func (d *Driver) writeBatch(_ string, entries []*batch.Entry) {
+	msgs := make([]kafka.Message, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Value.(*record).msg
+	}
+
+	err := d.w.WriteMessages(context.Background(), msgs...)
+
+	werrs, _ := err.(kafka.WriteErrors)
+	for i, e := range entries {
+		r := e.Value.(*record)
+		switch {
+		case werrs != nil:
+			if i < len(werrs) && werrs[i] != nil {
+				r.errCh <- werrs[i]
+			}
+		case err != nil:
+			r.errCh <- err
+		}
+		close(r.errCh)
+	}
+}
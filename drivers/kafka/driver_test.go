@@ -0,0 +1,195 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/izumin5210/pubee"
+	pubeekafka "github.com/izumin5210/pubee/drivers/kafka"
+)
+
+type fakeWriter struct {
+	mu        sync.Mutex
+	batches   [][]kafka.Message
+	writeFunc func([]kafka.Message) error
+	closed    bool
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.batches = append(w.batches, msgs)
+	if f := w.writeFunc; f != nil {
+		return f(msgs)
+	}
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func (w *fakeWriter) Messages() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var msgs []kafka.Message
+	for _, b := range w.batches {
+		msgs = append(msgs, b...)
+	}
+	return msgs
+}
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+	w := new(fakeWriter)
+	driver := pubeekafka.NewDriver(w, "awesometopic", pubeekafka.WithBatchTimeout(time.Hour))
+
+	errCh := driver.Publish(ctx, &pubee.Message{
+		Data:        []byte("test message"),
+		OrderingKey: "awesomekey",
+		Metadata:    map[string]string{"foo": "bar"},
+	})
+	driver.Publish(ctx, &pubee.Message{Data: []byte("test message"), Topic: "othertopic"})
+
+	if got, want := len(w.Messages()), 0; got != want {
+		t.Errorf("Written records before Flush() are %d, want %d", got, want)
+	}
+
+	driver.Flush()
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+
+	msgs := w.Messages()
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Written records are %d, want %d", got, want)
+	}
+	if got, want := msgs[0].Topic, "awesometopic"; got != want {
+		t.Errorf("Written record has topic %q, want %q", got, want)
+	}
+	if got, want := string(msgs[0].Key), "awesomekey"; got != want {
+		t.Errorf("Written record has key %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Headers, []kafka.Header{{Key: "foo", Value: []byte("bar")}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Written record has headers %v, want %v", got, want)
+	}
+	if got, want := msgs[1].Topic, "othertopic"; got != want {
+		t.Errorf("Written record has topic %q, want %q", got, want)
+	}
+	if msgs[1].Key != nil {
+		t.Errorf("Written record has key %q, want nil", msgs[1].Key)
+	}
+
+	err := driver.Close(ctx)
+	if err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+	if !w.closed {
+		t.Error("Writer should be closed")
+	}
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), pubeekafka.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestDriver_Batching(t *testing.T) {
+	ctx := context.Background()
+	w := new(fakeWriter)
+	driver := pubeekafka.NewDriver(w, "awesometopic",
+		pubeekafka.WithBatchSize(2),
+		pubeekafka.WithBatchTimeout(time.Hour),
+	)
+
+	var errChs []<-chan error
+	for i := 0; i < 5; i++ {
+		errChs = append(errChs, driver.Publish(ctx, &pubee.Message{Data: []byte{byte(i)}}))
+	}
+
+	driver.Close(ctx)
+
+	for _, errCh := range errChs {
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v, want nil", err)
+		}
+	}
+
+	var sizes []int
+	for _, b := range w.batches {
+		sizes = append(sizes, len(b))
+	}
+	total := 0
+	for _, n := range sizes {
+		if n > 2 {
+			t.Errorf("Written batch has %d records, want at most 2", n)
+		}
+		total += n
+	}
+	if got, want := total, 5; got != want {
+		t.Errorf("Written records are %d, want %d", got, want)
+	}
+	for i, msg := range w.Messages() {
+		if got, want := msg.Value, []byte{byte(i)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Written record #%d has value %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestDriver_WithInvalidBatchSize(t *testing.T) {
+	ctx := context.Background()
+	w := new(fakeWriter)
+	driver := pubeekafka.NewDriver(w, "awesometopic", pubeekafka.WithBatchSize(0), pubeekafka.WithBatchTimeout(0))
+
+	errCh := driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	driver.Close(ctx)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := len(w.Messages()), 1; got != want {
+		t.Errorf("Written records are %d, want %d", got, want)
+	}
+}
+
+func TestDriver_WhenWriteFailed(t *testing.T) {
+	ctx := context.Background()
+	w := &fakeWriter{
+		writeFunc: func(msgs []kafka.Message) error {
+			if len(msgs) == 1 {
+				return errors.New("unfortunate error")
+			}
+			werrs := make(kafka.WriteErrors, len(msgs))
+			werrs[1] = kafka.LeaderNotAvailable
+			return werrs
+		},
+	}
+	driver := pubeekafka.NewDriver(w, "awesometopic", pubeekafka.WithBatchTimeout(time.Hour))
+
+	errCh1 := driver.Publish(ctx, &pubee.Message{Data: []byte("test message1")})
+	errCh2 := driver.Publish(ctx, &pubee.Message{Data: []byte("test message2")})
+	driver.Flush()
+	errCh3 := driver.Publish(ctx, &pubee.Message{Data: []byte("test message3")})
+	driver.Close(ctx)
+
+	if err := <-errCh1; err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if got, want := <-errCh2, error(kafka.LeaderNotAvailable); got != want {
+		t.Errorf("Publish() returned %v, want %v", got, want)
+	}
+	if !pubee.IsRetryable(kafka.LeaderNotAvailable) {
+		t.Error("LeaderNotAvailable should be retryable")
+	}
+	if err := <-errCh3; err == nil {
+		t.Error("Publish() should return an error")
+	}
+}
@@ -0,0 +1,18 @@
+module github.com/izumin5210/pubee/drivers/kafka
+
+go 1.23.0
+
+require (
+	github.com/izumin5210/pubee v0.0.0
+	github.com/segmentio/kafka-go v0.4.51
+)
+
+require (
+	github.com/golang/protobuf v1.4.2 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	google.golang.org/protobuf v1.25.0 // indirect
+)
+
+replace github.com/izumin5210/pubee => ../../
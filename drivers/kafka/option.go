@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Config represents driver configuration.
+type Config struct {
+	BatchSize    int
+	BatchTimeout time.Duration
+	WriterFunc   func(*kafka.Writer)
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := new(Config)
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithBatchSize returns an Option that set the maximum number of records written at once. Defaults to 100, and non-positive values are ignored.
+func WithBatchSize(n int) Option {
+	return func(c *Config) {
+		c.BatchSize = n
+	}
+}
+
+// WithBatchTimeout returns an Option that set how long records are buffered before written. Defaults to 10ms, and non-positive values are ignored.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.BatchTimeout = d
+	}
+}
+
+// WithWriter returns an Option that configures kafka.Writer created by CreateDriver.
+func WithWriter(f func(*kafka.Writer)) Option {
+	return func(c *Config) {
+		c.WriterFunc = f
+	}
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// ErrClosed is returned when publishing messages to a closed Driver.
+var ErrClosed = errors.New("memory: driver is closed")
+
+// Driver is an in-memory pubee.Driver implementation.
+// It is safe for concurrent use, and useful for tests and local development.
+type Driver struct {
+	cfg *Config
+
+	mu          sync.Mutex
+	seq         int
+	messages    []*pubee.Message
+	topics      map[string][]*pubee.Message
+	failFunc    func(*pubee.Message) error
+	subscribers map[string][]*SubscriberDriver
+	notifyCh    chan struct{}
+	closed      bool
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+func NewDriver(opts ...Option) *Driver {
+	cfg := &Config{Topic: DefaultTopic}
+	cfg.apply(opts)
+
+	return &Driver{
+		cfg:         cfg,
+		topics:      map[string][]*pubee.Message{},
+		subscribers: map[string][]*SubscriberDriver{},
+		notifyCh:    make(chan struct{}),
+	}
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+	defer close(errCh)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		errCh <- ErrClosed
+		return errCh
+	}
+
+	if f := d.failFunc; f != nil {
+		if err := f(msg); err != nil {
+			errCh <- err
+			return errCh
+		}
+	}
+
+	d.seq++
+	msg.ID = strconv.Itoa(d.seq)
+
+	topic := msg.Topic
+	if topic == "" {
+		topic = d.cfg.Topic
+	}
+	d.messages = append(d.messages, msg)
+	d.topics[topic] = append(d.topics[topic], msg)
+
+	for _, s := range d.subscribers[topic] {
+		s.enqueue(&delivery{msg: msg, publishTime: time.Now()})
+	}
+
+	close(d.notifyCh)
+	d.notifyCh = make(chan struct{})
+
+	return errCh
+}
+
+func (d *Driver) Flush() {}
+
+func (d *Driver) Close(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	for _, subs := range d.subscribers {
+		for _, s := range subs {
+			s.stop()
+		}
+	}
+
+	return nil
+}
+
+// Messages returns all published messages in published order.
+func (d *Driver) Messages() []*pubee.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]*pubee.Message(nil), d.messages...)
+}
+
+// TopicMessages returns messages published to the given topic.
+func (d *Driver) TopicMessages(topic string) []*pubee.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]*pubee.Message(nil), d.topics[topic]...)
+}
+
+// WaitFor blocks until at least n messages are published or the context is done.
+func (d *Driver) WaitFor(ctx context.Context, n int) ([]*pubee.Message, error) {
+	for {
+		d.mu.Lock()
+		msgs := append([]*pubee.Message(nil), d.messages...)
+		notifyCh := d.notifyCh
+		d.mu.Unlock()
+
+		if len(msgs) >= n {
+			return msgs, nil
+		}
+
+		select {
+		case <-notifyCh:
+		case <-ctx.Done():
+			return msgs, ctx.Err()
+		}
+	}
+}
+
+// Reset removes all published messages and failure injection.
+func (d *Driver) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq = 0
+	d.messages = nil
+	d.topics = map[string][]*pubee.Message{}
+	d.failFunc = nil
+}
+
+// InjectFailure registers a function that decides whether publishing a message fails.
+// When f returns a non-nil error, the message is not stored and the error is sent to the error channel.
+func (d *Driver) InjectFailure(f func(*pubee.Message) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.failFunc = f
+}
+
+// NewSubscriberDriver returns a pubee.SubscriberDriver that receives messages published to the topic after it is created.
+func (d *Driver) NewSubscriberDriver(topic string) *SubscriberDriver {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := newSubscriberDriver()
+	if d.closed {
+		s.stop()
+	}
+	d.subscribers[topic] = append(d.subscribers[topic], s)
+
+	return s
+}
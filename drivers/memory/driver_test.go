@@ -0,0 +1,151 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver(memory.WithTopic("awesometopic"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message")}); err != nil {
+				t.Errorf("failed to publish a message: %v", err)
+			}
+		}()
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	msgs, err := driver.WaitFor(wctx, 10)
+	if err != nil {
+		t.Errorf("WaitFor() returned %v, want nil", err)
+	}
+	wg.Wait()
+
+	if got, want := len(msgs), 10; got != want {
+		t.Errorf("Published messages are %d, want %d", got, want)
+	}
+	if got, want := len(driver.TopicMessages("awesometopic")), 10; got != want {
+		t.Errorf("Published messages to the topic are %d, want %d", got, want)
+	}
+	if got, want := len(driver.TopicMessages(memory.DefaultTopic)), 0; got != want {
+		t.Errorf("Published messages to the default topic are %d, want %d", got, want)
+	}
+	for _, msg := range msgs {
+		if msg.ID == "" {
+			t.Error("Published message should have an ID")
+		}
+	}
+
+	driver.Reset()
+	if got, want := len(driver.Messages()), 0; got != want {
+		t.Errorf("Published messages are %d after Reset(), want %d", got, want)
+	}
+
+	err = driver.Close(ctx)
+	if err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), memory.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestDriver_WaitFor_Timeout(t *testing.T) {
+	driver := memory.NewDriver()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := driver.WaitFor(ctx, 1)
+	if got, want := err, context.DeadlineExceeded; got != want {
+		t.Errorf("WaitFor() returned %v, want %v", got, want)
+	}
+}
+
+func TestDriver_InjectFailure(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if string(msg.Data) == "fail" {
+			return errors.New("unfortunate error")
+		}
+		return nil
+	})
+
+	if err := <-driver.Publish(ctx, &pubee.Message{Data: []byte("ok")}); err != nil {
+		t.Errorf("Publish() returned %v, want nil", err)
+	}
+	if err := <-driver.Publish(ctx, &pubee.Message{Data: []byte("fail")}); err == nil {
+		t.Error("Publish() should return an error")
+	}
+	if got, want := len(driver.Messages()), 1; got != want {
+		t.Errorf("Published messages are %d, want %d", got, want)
+	}
+}
+
+func TestSubscriberDriver(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+
+	publisher := pubee.New(driver)
+	subscriber := pubee.NewSubscriber(driver.NewSubscriberDriver(memory.DefaultTopic))
+
+	var (
+		mu       sync.Mutex
+		received []string
+		failed   int
+	)
+	doneCh := make(chan struct{})
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var s string
+		msg.Unmarshal(&s)
+		if s == "retry" && failed == 0 {
+			failed++
+			return errors.New("temporary error")
+		}
+		received = append(received, s)
+		if len(received) == 2 {
+			close(doneCh)
+		}
+		return nil
+	})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- subscriber.Subscribe(ctx) }()
+
+	publisher.Publish(ctx, "foobarbaz")
+	publisher.Publish(ctx, "retry")
+
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("messages were not received")
+	}
+
+	subscriber.Close(ctx)
+	publisher.Close(ctx)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe() returned %v, want nil", err)
+	}
+	if got, want := received, []string{"foobarbaz", "retry"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Received messages are %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+package memory
+
+// DefaultTopic is the topic name used when no topic is specified.
+const DefaultTopic = "default"
+
+// Config represents driver configuration.
+type Config struct {
+	Topic string
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithTopic returns an Option that set a topic name messages without pubee.WithTopic are stored to.
+func WithTopic(topic string) Option {
+	return func(c *Config) {
+		c.Topic = topic
+	}
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+type delivery struct {
+	msg         *pubee.Message
+	publishTime time.Time
+}
+
+// SubscriberDriver is an in-memory pubee.SubscriberDriver implementation.
+// Nacked messages are redelivered.
+type SubscriberDriver struct {
+	mu       sync.Mutex
+	queue    []*delivery
+	notifyCh chan struct{}
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+var _ pubee.SubscriberDriver = (*SubscriberDriver)(nil)
+
+func newSubscriberDriver() *SubscriberDriver {
+	return &SubscriberDriver{
+		notifyCh: make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (s *SubscriberDriver) Receive(ctx context.Context, f func(context.Context, *pubee.ReceivedMessage)) error {
+	for {
+		d, ok := s.dequeue()
+		if !ok {
+			select {
+			case <-s.notifyCh:
+				continue
+			case <-s.stopCh:
+				return nil
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		f(ctx, &pubee.ReceivedMessage{
+			ID:          d.msg.ID,
+			OrderingKey: d.msg.OrderingKey,
+			Data:        d.msg.Data,
+			Metadata:    d.msg.Metadata,
+			PublishTime: d.publishTime,
+			Acker:       &acker{sub: s, d: d},
+		})
+	}
+}
+
+func (s *SubscriberDriver) Close(context.Context) error {
+	s.stop()
+	return nil
+}
+
+func (s *SubscriberDriver) enqueue(d *delivery) {
+	s.mu.Lock()
+	s.queue = append(s.queue, d)
+	s.mu.Unlock()
+
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (s *SubscriberDriver) dequeue() (*delivery, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+	d := s.queue[0]
+	s.queue = s.queue[1:]
+	return d, true
+}
+
+func (s *SubscriberDriver) stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+type acker struct {
+	sub *SubscriberDriver
+	d   *delivery
+}
+
+func (a *acker) Ack() {}
+
+func (a *acker) Nack() {
+	a.sub.enqueue(a.d)
+}
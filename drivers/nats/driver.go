@@ -0,0 +1,111 @@
+// Package nats provides pubee.Driver implementations for core NATS and NATS JetStream.
+//
+// Message metadata are mapped to NATS headers, and topics specified by pubee.WithTopic are used as subjects.
+package nats
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/izumin5210/pubee"
+)
+
+// ErrClosed is returned when publishing messages to a closed driver.
+var ErrClosed = errors.New("nats: driver is closed")
+
+// Driver publishes messages with core NATS. Messages are delivered at most once.
+type Driver struct {
+	nc             *nats.Conn
+	defaultSubject string
+	cfg            *Config
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+// CreateDriver connects to the NATS server and creates a Driver.
+// Messages are published to subject by default, and to subjects specified by pubee.WithTopic.
+func CreateDriver(ctx context.Context, url, subject string, opts ...Option) (*Driver, error) {
+	cfg := newConfig(opts)
+
+	nc, err := nats.Connect(url, cfg.ConnOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDriver(nc, subject, opts...), nil
+}
+
+// NewDriver creates a Driver that publishes messages over the connection.
+// The connection is closed when the Driver is closed.
+func NewDriver(nc *nats.Conn, subject string, opts ...Option) *Driver {
+	return &Driver{
+		nc:             nc,
+		defaultSubject: subject,
+		cfg:            newConfig(opts),
+	}
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+	defer close(errCh)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		errCh <- ErrClosed
+		return errCh
+	}
+
+	err := d.nc.PublishMsg(newMsg(msg, d.defaultSubject))
+	if err != nil {
+		errCh <- err
+	}
+
+	return errCh
+}
+
+// Flush waits for the server to process all buffered messages.
+func (d *Driver) Flush() {
+	d.nc.Flush()
+}
+
+func (d *Driver) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	return closeConn(ctx, d.nc)
+}
+
+func newMsg(msg *pubee.Message, defaultSubject string) *nats.Msg {
+	m := &nats.Msg{
+		Subject: defaultSubject,
+		Data:    msg.Data,
+	}
+	if msg.Topic != "" {
+		m.Subject = msg.Topic
+	}
+	if len(msg.Metadata) > 0 {
+		m.Header = make(nats.Header, len(msg.Metadata))
+		for k, v := range msg.Metadata {
+			m.Header.Set(k, v)
+		}
+	}
+	return m
+}
+
+func closeConn(ctx context.Context, nc *nats.Conn) error {
+	defer nc.Close()
+	return nc.FlushWithContext(ctx)
+}
@@ -0,0 +1,147 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/izumin5210/pubee"
+	pubeenats "github.com/izumin5210/pubee/drivers/nats"
+)
+
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+
+	return natstest.RunServer(&opts)
+}
+
+func connect(t *testing.T, srv *server.Server) *nats.Conn {
+	t.Helper()
+
+	nc, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect nats-server: %v", err)
+	}
+
+	return nc
+}
+
+func TestDriver(t *testing.T) {
+	srv := runServer(t)
+	defer srv.Shutdown()
+
+	ctx := context.Background()
+
+	nc := connect(t, srv)
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync("awesome.>")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	nc.Flush()
+
+	driver, err := pubeenats.CreateDriver(ctx, srv.ClientURL(), "awesome.default")
+	if err != nil {
+		t.Fatalf("CreateDriver() returned %v", err)
+	}
+
+	publisher := pubee.New(driver, pubee.WithMetadata("foo", "bar"))
+
+	if _, err := publisher.Publish(ctx, "test message").Get(ctx); err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+	if _, err := publisher.Publish(ctx, "test message", pubee.WithTopic("awesome.other")).Get(ctx); err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+
+	publisher.Close(ctx)
+
+	for _, subject := range []string{"awesome.default", "awesome.other"} {
+		msg, err := sub.NextMsg(time.Second)
+		if err != nil {
+			t.Fatalf("failed to receive a message: %v", err)
+		}
+		if got, want := msg.Subject, subject; got != want {
+			t.Errorf("Received message has subject %q, want %q", got, want)
+		}
+		if got, want := string(msg.Data), "test message"; got != want {
+			t.Errorf("Received message has data %q, want %q", got, want)
+		}
+		if got, want := msg.Header.Get("foo"), "bar"; got != want {
+			t.Errorf("Received message has header foo=%q, want %q", got, want)
+		}
+	}
+
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), pubeenats.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestJetStreamDriver(t *testing.T) {
+	srv := runServer(t)
+	defer srv.Shutdown()
+
+	ctx := context.Background()
+
+	driver, err := pubeenats.CreateJetStreamDriver(ctx, srv.ClientURL(), "awesome.default",
+		pubeenats.WithCreateStreamIfNeeded(jetstream.StreamConfig{
+			Name:     "AWESOME",
+			Subjects: []string{"awesome.>"},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("CreateJetStreamDriver() returned %v", err)
+	}
+
+	publisher := pubee.New(driver, pubee.WithMetadata("foo", "bar"))
+
+	id, err := publisher.Publish(ctx, "test message").Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+	if got, want := id, "1"; got != want {
+		t.Errorf("Published message has ID %q, want %q", got, want)
+	}
+
+	_, err = publisher.Publish(ctx, "test message", pubee.WithTopic("unknown.subject")).Get(ctx)
+	if err == nil {
+		t.Error("Publish() to a subject without streams should return an error")
+	}
+
+	publisher.Close(ctx)
+
+	nc := connect(t, srv)
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("failed to create JetStream context: %v", err)
+	}
+	stream, err := js.Stream(ctx, "AWESOME")
+	if err != nil {
+		t.Fatalf("failed to get stream: %v", err)
+	}
+	msg, err := stream.GetMsg(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get a message: %v", err)
+	}
+	if got, want := msg.Subject, "awesome.default"; got != want {
+		t.Errorf("Stored message has subject %q, want %q", got, want)
+	}
+	if got, want := string(msg.Data), "test message"; got != want {
+		t.Errorf("Stored message has data %q, want %q", got, want)
+	}
+	if got, want := msg.Header.Get("foo"), "bar"; got != want {
+		t.Errorf("Stored message has header foo=%q, want %q", got, want)
+	}
+}
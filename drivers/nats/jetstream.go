@@ -0,0 +1,124 @@
+package nats
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/izumin5210/pubee"
+)
+
+// JetStreamDriver publishes messages to JetStream streams and waits for acknowledgements.
+// Errors returned by the server, such as no stream matching the subject, are sent to the error channel.
+type JetStreamDriver struct {
+	js             jetstream.JetStream
+	defaultSubject string
+	cfg            *Config
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+var _ pubee.Driver = (*JetStreamDriver)(nil)
+
+// CreateJetStreamDriver connects to the NATS server and creates a JetStreamDriver.
+func CreateJetStreamDriver(ctx context.Context, url, subject string, opts ...Option) (*JetStreamDriver, error) {
+	cfg := newConfig(opts)
+
+	nc, err := nats.Connect(url, cfg.ConnOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	d, err := NewJetStreamDriver(ctx, js, subject, opts...)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// NewJetStreamDriver creates a JetStreamDriver that publishes messages with the JetStream context.
+// The underlying connection is closed when the JetStreamDriver is closed.
+func NewJetStreamDriver(ctx context.Context, js jetstream.JetStream, subject string, opts ...Option) (*JetStreamDriver, error) {
+	cfg := newConfig(opts)
+
+	if sc := cfg.StreamConfig; sc != nil {
+		_, err := js.CreateOrUpdateStream(ctx, *sc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &JetStreamDriver{
+		js:             js,
+		defaultSubject: subject,
+		cfg:            cfg,
+	}, nil
+}
+
+func (d *JetStreamDriver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		errCh <- ErrClosed
+		close(errCh)
+		return errCh
+	}
+
+	f, err := d.js.PublishMsgAsync(newMsg(msg, d.defaultSubject))
+	if err != nil {
+		errCh <- err
+		close(errCh)
+		return errCh
+	}
+
+	go func() {
+		defer close(errCh)
+		select {
+		case ack := <-f.Ok():
+			msg.ID = strconv.FormatUint(ack.Sequence, 10)
+		case err := <-f.Err():
+			errCh <- err
+		}
+	}()
+
+	return errCh
+}
+
+// Flush waits for acknowledgements of all published messages.
+func (d *JetStreamDriver) Flush() {
+	<-d.js.PublishAsyncComplete()
+}
+
+func (d *JetStreamDriver) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	select {
+	case <-d.js.PublishAsyncComplete():
+	case <-ctx.Done():
+		d.js.Conn().Close()
+		return ctx.Err()
+	}
+
+	return closeConn(ctx, d.js.Conn())
+}
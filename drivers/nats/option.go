@@ -0,0 +1,41 @@
+package nats
+
+import (
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Config represents driver configuration.
+type Config struct {
+	ConnOptions  []nats.Option
+	StreamConfig *jetstream.StreamConfig
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := new(Config)
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithConnOptions returns an Option that set options for connections created by CreateDriver or CreateJetStreamDriver.
+func WithConnOptions(opts ...nats.Option) Option {
+	return func(c *Config) {
+		c.ConnOptions = append(c.ConnOptions, opts...)
+	}
+}
+
+// WithCreateStreamIfNeeded returns an Option that creates or updates the stream when a JetStreamDriver is created.
+func WithCreateStreamIfNeeded(cfg jetstream.StreamConfig) Option {
+	return func(c *Config) {
+		c.StreamConfig = &cfg
+	}
+}
@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// Dialect represents differences of SQL between databases.
+type Dialect struct {
+	// Placeholder returns a bind parameter for the n-th (1-origin) argument.
+	Placeholder func(n int) string
+	// CreateTable is a statement to create an outbox table. `%s` is replaced with the table name.
+	CreateTable string
+}
+
+var (
+	SQLite = Dialect{
+		Placeholder: questionPlaceholder,
+		CreateTable: `CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	topic TEXT NOT NULL,
+	ordering_key TEXT NOT NULL,
+	data BLOB NOT NULL,
+	metadata TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	claimed_by TEXT,
+	claimed_until INTEGER,
+	sent_at INTEGER
+)`,
+	}
+
+	MySQL = Dialect{
+		Placeholder: questionPlaceholder,
+		CreateTable: `CREATE TABLE IF NOT EXISTS %s (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	topic VARCHAR(255) NOT NULL,
+	ordering_key VARCHAR(255) NOT NULL,
+	data LONGBLOB NOT NULL,
+	metadata TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	claimed_by VARCHAR(255),
+	claimed_until BIGINT,
+	sent_at BIGINT
+)`,
+	}
+
+	Postgres = Dialect{
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		CreateTable: `CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	topic TEXT NOT NULL,
+	ordering_key TEXT NOT NULL,
+	data BYTEA NOT NULL,
+	metadata TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	claimed_by TEXT,
+	claimed_until BIGINT,
+	sent_at BIGINT
+)`,
+	}
+)
+
+func questionPlaceholder(int) string { return "?" }
+
+// CreateTable creates an outbox table when it does not exist.
+func CreateTable(ctx context.Context, db *sql.DB, opts ...Option) error {
+	cfg := newConfig(opts)
+	_, err := db.ExecContext(ctx, fmt.Sprintf(cfg.Dialect.CreateTable, cfg.Table))
+	return err
+}
@@ -0,0 +1,79 @@
+// Package outbox implements the transactional outbox pattern.
+// Driver writes messages into an outbox table within a caller-supplied transaction,
+// and Relay forwards them to another pubee.Driver.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// ErrNoTx is returned when publishing without a transaction in the context.
+var ErrNoTx = errors.New("outbox: no transaction in context")
+
+type ctxkeyTx struct{}
+
+// SetTx returns a context holding tx. Messages published with the context are written within tx.
+func SetTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, ctxkeyTx{}, tx)
+}
+
+// GetTx returns a transaction set by SetTx.
+func GetTx(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(ctxkeyTx{}).(*sql.Tx)
+	return tx
+}
+
+type Driver struct {
+	cfg *Config
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+func NewDriver(opts ...Option) *Driver {
+	return &Driver{cfg: newConfig(opts)}
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+	defer close(errCh)
+
+	tx := GetTx(ctx)
+	if tx == nil {
+		errCh <- ErrNoTx
+		return errCh
+	}
+
+	md, err := json.Marshal(msg.Metadata)
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	ph := d.cfg.Dialect.Placeholder
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (topic, ordering_key, data, metadata, created_at) VALUES (%s, %s, %s, %s, %s)", d.cfg.Table, ph(1), ph(2), ph(3), ph(4), ph(5)),
+		msg.Topic, msg.OrderingKey, msg.Data, string(md), time.Now().UnixNano(),
+	)
+	if err != nil {
+		errCh <- err
+		return errCh
+	}
+
+	if id, err := res.LastInsertId(); err == nil {
+		msg.ID = strconv.FormatInt(id, 10)
+	}
+
+	return errCh
+}
+
+func (d *Driver) Flush() {}
+
+func (d *Driver) Close(context.Context) error { return nil }
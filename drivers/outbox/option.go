@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Config represents outbox configuration.
+type Config struct {
+	Table         string
+	Dialect       Dialect
+	RelayID       string
+	BatchSize     int
+	PollInterval  time.Duration
+	LeaseDuration time.Duration
+	MarkSent      bool
+	ErrorLog      pubee.Logger
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		Table:         "pubee_outbox",
+		Dialect:       SQLite,
+		BatchSize:     100,
+		PollInterval:  time.Second,
+		LeaseDuration: 30 * time.Second,
+		ErrorLog:      log.New(os.Stderr, "[pubee]", log.LstdFlags),
+	}
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is outbox Option
+type Option func(*Config)
+
+// WithTable returns an Option that set the outbox table name. Defaults to "pubee_outbox".
+func WithTable(name string) Option {
+	return func(c *Config) {
+		c.Table = name
+	}
+}
+
+// WithDialect returns an Option that set the SQL Dialect. Defaults to SQLite.
+func WithDialect(d Dialect) Option {
+	return func(c *Config) {
+		c.Dialect = d
+	}
+}
+
+// WithRelayID returns an Option that set an identifier of the Relay. A random ID is used by default.
+func WithRelayID(id string) Option {
+	return func(c *Config) {
+		c.RelayID = id
+	}
+}
+
+// WithBatchSize returns an Option that set the maximum number of messages forwarded by a poll.
+func WithBatchSize(n int) Option {
+	return func(c *Config) {
+		c.BatchSize = n
+	}
+}
+
+// WithPollInterval returns an Option that set an interval to poll the outbox table.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.PollInterval = d
+	}
+}
+
+// WithLeaseDuration returns an Option that set how long a Relay holds claimed messages.
+// Messages are redelivered by other relays when the lease expires.
+func WithLeaseDuration(d time.Duration) Option {
+	return func(c *Config) {
+		c.LeaseDuration = d
+	}
+}
+
+// WithMarkSent returns an Option that keeps forwarded messages marked as sent instead of deleting them.
+func WithMarkSent() Option {
+	return func(c *Config) {
+		c.MarkSent = true
+	}
+}
+
+// WithErrorLog returns an Option that set a Logger for errors occurred in a Relay.
+func WithErrorLog(l pubee.Logger) Option {
+	return func(c *Config) {
+		c.ErrorLog = l
+	}
+}
@@ -0,0 +1,229 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+	"github.com/izumin5210/pubee/drivers/outbox"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "pubee-outbox")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "outbox.db"))
+	if err != nil {
+		t.Fatalf("failed to open a database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = outbox.CreateTable(context.Background(), db)
+	if err != nil {
+		t.Fatalf("failed to create an outbox table: %v", err)
+	}
+
+	return db
+}
+
+func publish(t *testing.T, db *sql.DB, commit bool, bodies ...string) {
+	t.Helper()
+
+	ctx := context.Background()
+	publisher := pubee.New(outbox.NewDriver(), pubee.WithTopic("awesometopic"), pubee.WithOrderingKey("awesomekey"), pubee.WithMetadata("foo", "bar"))
+	defer publisher.Close(ctx)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin a transaction: %v", err)
+	}
+
+	txCtx := outbox.SetTx(ctx, tx)
+	for _, body := range bodies {
+		if _, err := publisher.Publish(txCtx, body).Get(ctx); err != nil {
+			t.Fatalf("failed to publish a message: %v", err)
+		}
+	}
+
+	if commit {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	if err != nil {
+		t.Fatalf("failed to finish a transaction: %v", err)
+	}
+}
+
+func countRows(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM pubee_outbox").Scan(&n)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return n
+}
+
+func dataOf(msgs []*pubee.Message) []string {
+	var got []string
+	for _, msg := range msgs {
+		got = append(got, string(msg.Data))
+	}
+	return got
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	publish(t, db, false, "rollbacked")
+	if got, want := countRows(t, db), 0; got != want {
+		t.Errorf("outbox has %d rows after rollback, want %d", got, want)
+	}
+
+	publish(t, db, true, "foo", "bar", "baz")
+	if got, want := countRows(t, db), 3; got != want {
+		t.Errorf("outbox has %d rows after commit, want %d", got, want)
+	}
+
+	driver := memory.NewDriver()
+	relay := outbox.NewRelay(db, driver)
+
+	n, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Errorf("RelayOnce() returned %v, want nil", err)
+	}
+	if got, want := n, 3; got != want {
+		t.Errorf("RelayOnce() forwarded %d messages, want %d", got, want)
+	}
+	if got, want := dataOf(driver.Messages()), []string{"foo", "bar", "baz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Forwarded messages are %v, want %v", got, want)
+	}
+	if got, want := len(driver.TopicMessages("awesometopic")), 3; got != want {
+		t.Errorf("Forwarded messages to the topic are %d, want %d", got, want)
+	}
+	if got, want := driver.Messages()[0].OrderingKey, "awesomekey"; got != want {
+		t.Errorf("Forwarded message has ordering key %q, want %q", got, want)
+	}
+	if got, want := driver.Messages()[0].Metadata, map[string]string{"foo": "bar", "content-type": "text/plain; charset=utf-8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Forwarded message has metadata %v, want %v", got, want)
+	}
+	if got, want := countRows(t, db), 0; got != want {
+		t.Errorf("outbox has %d rows after relay, want %d", got, want)
+	}
+}
+
+func TestRelay_WhenFailed(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	publish(t, db, true, "foo", "bar", "baz")
+
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if string(msg.Data) == "bar" {
+			return errors.New("unfortunate error")
+		}
+		return nil
+	})
+	relay := outbox.NewRelay(db, driver, outbox.WithErrorLog(log.New(ioutil.Discard, "", 0)))
+
+	n, err := relay.RelayOnce(ctx)
+	if err == nil {
+		t.Error("RelayOnce() should return an error")
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("RelayOnce() forwarded %d messages, want %d", got, want)
+	}
+
+	driver.InjectFailure(nil)
+
+	n, err = relay.RelayOnce(ctx)
+	if err != nil {
+		t.Errorf("RelayOnce() returned %v, want nil", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("RelayOnce() forwarded %d messages, want %d", got, want)
+	}
+	if got, want := dataOf(driver.Messages()), []string{"foo", "bar", "baz"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Forwarded messages are %v, want %v", got, want)
+	}
+}
+
+func TestRelay_WithMultipleRelays(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	publish(t, db, true, "foo", "bar")
+
+	other := outbox.NewRelay(db, memory.NewDriver())
+
+	var otherForwarded []int
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		n, err := other.RelayOnce(ctx)
+		if err != nil {
+			t.Errorf("RelayOnce() returned %v, want nil", err)
+		}
+		otherForwarded = append(otherForwarded, n)
+		return nil
+	})
+	relay := outbox.NewRelay(db, driver)
+
+	n, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Errorf("RelayOnce() returned %v, want nil", err)
+	}
+	if got, want := n, 2; got != want {
+		t.Errorf("RelayOnce() forwarded %d messages, want %d", got, want)
+	}
+	if got, want := otherForwarded, []int{0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Other relay forwarded %v messages, want %v", got, want)
+	}
+}
+
+func TestDriver_WithoutTx(t *testing.T) {
+	err := <-outbox.NewDriver().Publish(context.Background(), &pubee.Message{Data: []byte("foo")})
+	if got, want := err, outbox.ErrNoTx; got != want {
+		t.Errorf("Publish() returned %v, want %v", got, want)
+	}
+}
+
+func TestRelay_WithMarkSent(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	publish(t, db, true, "foo")
+
+	driver := memory.NewDriver()
+	relay := outbox.NewRelay(db, driver, outbox.WithMarkSent())
+
+	for i := 0; i < 2; i++ {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Errorf("RelayOnce() returned %v, want nil", err)
+		}
+	}
+	if got, want := len(driver.Messages()), 1; got != want {
+		t.Errorf("Forwarded messages are %d, want %d", got, want)
+	}
+	if got, want := countRows(t, db), 1; got != want {
+		t.Errorf("outbox has %d rows, want %d", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// Relay forwards messages in the outbox table to a pubee.Driver in order of their IDs.
+//
+// Messages are delivered at least once: a message can be forwarded again when deleting it fails or
+// the lease of the claiming relay expires.
+// Relays running on several replicas can share a table. Only the relay claiming the oldest message
+// forwards messages at a time, so that the order of messages is kept.
+type Relay struct {
+	db     *sql.DB
+	driver pubee.Driver
+	cfg    *Config
+}
+
+func NewRelay(db *sql.DB, d pubee.Driver, opts ...Option) *Relay {
+	cfg := newConfig(opts)
+	if cfg.RelayID == "" {
+		cfg.RelayID = randomID()
+	}
+	return &Relay{
+		db:     db,
+		driver: d,
+		cfg:    cfg,
+	}
+}
+
+// Run polls the outbox table and forwards messages until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	t := time.NewTicker(r.cfg.PollInterval)
+	defer t.Stop()
+
+	for {
+		for {
+			n, err := r.RelayOnce(ctx)
+			if err != nil {
+				r.cfg.ErrorLog.Printf("failed to relay outbox messages: %v", err)
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+type row struct {
+	id       int64
+	topic    string
+	key      string
+	data     []byte
+	metadata string
+}
+
+// RelayOnce claims a batch of messages and forwards them. It returns the number of forwarded messages.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	rows, err := r.claim(ctx)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	for i, row := range rows {
+		msg := &pubee.Message{Topic: row.topic, OrderingKey: row.key, Data: row.data}
+		if err := json.Unmarshal([]byte(row.metadata), &msg.Metadata); err != nil {
+			r.release(ctx)
+			return i, err
+		}
+
+		if err := <-r.driver.Publish(ctx, msg); err != nil {
+			r.release(ctx)
+			return i, err
+		}
+
+		if err := r.done(ctx, row.id); err != nil {
+			r.release(ctx)
+			return i, err
+		}
+	}
+
+	return len(rows), nil
+}
+
+func (r *Relay) claim(ctx context.Context) ([]*row, error) {
+	ph := r.cfg.Dialect.Placeholder
+	now := time.Now()
+
+	rs, err := r.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT id, topic, ordering_key, data, metadata FROM %s WHERE sent_at IS NULL ORDER BY id LIMIT %d", r.cfg.Table, r.cfg.BatchSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*row
+	for rs.Next() {
+		row := new(row)
+		if err := rs.Scan(&row.id, &row.topic, &row.key, &row.data, &row.metadata); err != nil {
+			rs.Close()
+			return nil, err
+		}
+		candidates = append(candidates, row)
+	}
+	rs.Close()
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+
+	// claim messages from the oldest one, and stop at the message claimed by other relays to keep the order.
+	var claimed []*row
+	for _, row := range candidates {
+		res, err := r.db.ExecContext(ctx,
+			fmt.Sprintf(
+				"UPDATE %s SET claimed_by = %s, claimed_until = %s WHERE id = %s AND sent_at IS NULL AND (claimed_by IS NULL OR claimed_by = %s OR claimed_until < %s)",
+				r.cfg.Table, ph(1), ph(2), ph(3), ph(4), ph(5),
+			),
+			r.cfg.RelayID, now.Add(r.cfg.LeaseDuration).UnixNano(), row.id, r.cfg.RelayID, now.UnixNano(),
+		)
+		if err != nil {
+			return claimed, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return claimed, err
+		} else if n == 0 {
+			break
+		}
+		claimed = append(claimed, row)
+	}
+
+	return claimed, nil
+}
+
+func (r *Relay) done(ctx context.Context, id int64) error {
+	ph := r.cfg.Dialect.Placeholder
+	var err error
+	if r.cfg.MarkSent {
+		_, err = r.db.ExecContext(ctx,
+			fmt.Sprintf("UPDATE %s SET sent_at = %s, claimed_by = NULL, claimed_until = NULL WHERE id = %s", r.cfg.Table, ph(1), ph(2)),
+			time.Now().UnixNano(), id,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE id = %s", r.cfg.Table, ph(1)),
+			id,
+		)
+	}
+	return err
+}
+
+func (r *Relay) release(ctx context.Context) {
+	ph := r.cfg.Dialect.Placeholder
+	_, err := r.db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET claimed_by = NULL, claimed_until = NULL WHERE claimed_by = %s AND sent_at IS NULL", r.cfg.Table, ph(1)),
+		r.cfg.RelayID,
+	)
+	if err != nil {
+		r.cfg.ErrorLog.Printf("failed to release outbox messages: %v", err)
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,153 @@
+// Package redisstream provides a pubee.Driver for Redis Streams.
+//
+// Messages are added with XADD. Message data are stored in the data field,
+// and message metadata are stored in the other fields.
+// Buffered messages are pipelined, so that a batch is sent in a round trip.
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/internal/batch"
+)
+
+// ErrClosed is returned when publishing messages to a closed Driver.
+var ErrClosed = errors.New("redisstream: driver is closed")
+
+type entry struct {
+	msg   *pubee.Message
+	args  *redis.XAddArgs
+	errCh chan error
+}
+
+type Driver struct {
+	client        redis.UniversalClient
+	defaultStream string
+	cfg           *Config
+	b             *batch.Batcher
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+// CreateDriver connects to the Redis server specified by url, such as redis://localhost:6379/0, and creates a Driver.
+// Messages are added to stream by default, and to streams specified by pubee.WithTopic.
+func CreateDriver(ctx context.Context, url, stream string, opts ...Option) (*Driver, error) {
+	redisOpts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(redisOpts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return NewDriver(client, stream, opts...), nil
+}
+
+// NewDriver creates a Driver that adds messages with the client.
+// The client is closed when the Driver is closed.
+func NewDriver(client redis.UniversalClient, stream string, opts ...Option) *Driver {
+	d := &Driver{
+		client:        client,
+		defaultStream: stream,
+		cfg:           newConfig(opts),
+	}
+	d.b = batch.New(d.writeBatch, batch.Config{
+		MaxEntries: d.cfg.BatchSize,
+		Timeout:    d.cfg.BatchTimeout,
+	})
+
+	return d
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	args, err := d.newArgs(msg)
+	if err != nil {
+		errCh <- err
+		close(errCh)
+		return errCh
+	}
+
+	if !d.b.Add(&batch.Entry{Value: &entry{msg: msg, args: args, errCh: errCh}}) {
+		errCh <- ErrClosed
+		close(errCh)
+	}
+
+	return errCh
+}
+
+// Flush adds all buffered messages and waits for their results.
+func (d *Driver) Flush() {
+	d.b.Flush()
+}
+
+func (d *Driver) Close(ctx context.Context) error {
+	if !d.b.Close() {
+		return nil
+	}
+	return d.client.Close()
+}
+
+func (d *Driver) newArgs(msg *pubee.Message) (*redis.XAddArgs, error) {
+	stream := msg.Topic
+	if stream == "" {
+		stream = d.defaultStream
+	}
+	if stream == "" {
+		return nil, errors.New("redisstream: stream is not specified")
+	}
+
+	keys := make([]string, 0, len(msg.Metadata))
+	for k := range msg.Metadata {
+		if k == d.cfg.DataField {
+			return nil, fmt.Errorf("redisstream: metadata %q conflicts with the data field", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]interface{}, 0, 2*len(keys)+2)
+	values = append(values, d.cfg.DataField, msg.Data)
+	for _, k := range keys {
+		values = append(values, k, msg.Metadata[k])
+	}
+
+	return &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: d.cfg.MaxLen,
+		Approx: d.cfg.Approx,
+		Values: values,
+	}, nil
+}
+
+func (d *Driver) writeBatch(_ string, entries []*batch.Entry) {
+	ctx := context.Background()
+
+	pipe := d.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(entries))
+	for i, be := range entries {
+		cmds[i] = pipe.XAdd(ctx, be.Value.(*entry).args)
+	}
+	pipe.Exec(ctx)
+
+	for i, be := range entries {
+		e := be.Value.(*entry)
+		id, err := cmds[i].Result()
+		if err != nil {
+			e.errCh <- err
+		} else {
+			e.msg.ID = id
+		}
+		close(e.errCh)
+	}
+}
@@ -0,0 +1,157 @@
+package redisstream_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/redisstream"
+)
+
+func TestDriver(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	driver, err := redisstream.CreateDriver(ctx, "redis://"+mr.Addr(), "awesomestream")
+	if err != nil {
+		t.Fatalf("CreateDriver() returned %v", err)
+	}
+
+	publisher := pubee.New(driver, pubee.WithMetadata("foo", "bar"))
+
+	id, err := publisher.Publish(ctx, "test message").Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+	_, err = publisher.Publish(ctx, "test message", pubee.WithTopic("otherstream")).Get(ctx)
+	if err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+
+	publisher.Close(ctx)
+
+	entries, err := mr.Stream("awesomestream")
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if got, want := len(entries), 1; got != want {
+		t.Fatalf("Stream has %d entries, want %d", got, want)
+	}
+	if got, want := entries[0].ID, id; got != want {
+		t.Errorf("Stream entry has ID %q, want %q", got, want)
+	}
+	if got, want := strings.Join(entries[0].Values, ","), "data,test message,content-type,text/plain; charset=utf-8,foo,bar"; got != want {
+		t.Errorf("Stream entry has fields %q, want %q", got, want)
+	}
+
+	entries, err = mr.Stream("otherstream")
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if got, want := len(entries), 1; got != want {
+		t.Errorf("Stream has %d entries, want %d", got, want)
+	}
+
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), redisstream.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestDriver_Flush(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	driver := redisstream.NewDriver(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "awesomestream",
+		redisstream.WithBatchTimeout(time.Hour),
+	)
+	defer driver.Close(ctx)
+
+	var errChs []<-chan error
+	for i := 0; i < 3; i++ {
+		errChs = append(errChs, driver.Publish(ctx, &pubee.Message{Data: []byte("test message")}))
+	}
+
+	if mr.Exists("awesomestream") {
+		t.Error("Messages should be buffered until Flush()")
+	}
+
+	driver.Flush()
+
+	for _, errCh := range errChs {
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v", err)
+		}
+	}
+
+	entries, err := mr.Stream("awesomestream")
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if got, want := len(entries), 3; got != want {
+		t.Errorf("Stream has %d entries, want %d", got, want)
+	}
+}
+
+func TestDriver_WithMaxLen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	driver := redisstream.NewDriver(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "awesomestream",
+		redisstream.WithMaxLen(2),
+	)
+
+	for i := 0; i < 5; i++ {
+		driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	}
+	driver.Close(ctx)
+
+	entries, err := mr.Stream("awesomestream")
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if got, want := len(entries), 2; got != want {
+		t.Errorf("Stream has %d entries, want %d", got, want)
+	}
+}
+
+func TestDriver_WithInvalidBatchSize(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	driver := redisstream.NewDriver(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "awesomestream",
+		redisstream.WithBatchSize(0),
+		redisstream.WithBatchTimeout(0),
+	)
+
+	errCh := driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+	driver.Close(ctx)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+	entries, err := mr.Stream("awesomestream")
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if got, want := len(entries), 1; got != want {
+		t.Errorf("Stream has %d entries, want %d", got, want)
+	}
+}
+
+func TestDriver_WhenMetadataConflicts(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	driver := redisstream.NewDriver(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "awesomestream")
+	defer driver.Close(ctx)
+
+	err := <-driver.Publish(ctx, &pubee.Message{Metadata: map[string]string{"data": "foo"}})
+	if err == nil {
+		t.Error("Publish() should return an error")
+	}
+}
@@ -0,0 +1,69 @@
+package redisstream
+
+import (
+	"time"
+)
+
+// Config represents driver configuration.
+type Config struct {
+	DataField    string
+	MaxLen       int64
+	Approx       bool
+	BatchSize    int
+	BatchTimeout time.Duration
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		DataField: "data",
+	}
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithDataField returns an Option that set the stream field name for message data.
+func WithDataField(name string) Option {
+	return func(c *Config) {
+		c.DataField = name
+	}
+}
+
+// WithMaxLen returns an Option that trims streams to n entries with MAXLEN.
+func WithMaxLen(n int64) Option {
+	return func(c *Config) {
+		c.MaxLen = n
+		c.Approx = false
+	}
+}
+
+// WithApproxMaxLen returns an Option that trims streams to about n entries with MAXLEN ~.
+// It is more efficient than WithMaxLen.
+func WithApproxMaxLen(n int64) Option {
+	return func(c *Config) {
+		c.MaxLen = n
+		c.Approx = true
+	}
+}
+
+// WithBatchSize returns an Option that set the maximum number of XADD commands pipelined at once. Defaults to 100, and non-positive values are ignored.
+func WithBatchSize(n int) Option {
+	return func(c *Config) {
+		c.BatchSize = n
+	}
+}
+
+// WithBatchTimeout returns an Option that set how long messages are buffered before added. Defaults to 10ms, and non-positive values are ignored.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.BatchTimeout = d
+	}
+}
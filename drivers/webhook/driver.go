@@ -0,0 +1,234 @@
+// Package webhook provides a pubee.Driver that POSTs messages to HTTP endpoints.
+//
+// Message data are sent as request bodies, and message metadata are sent as request headers.
+// Combined with CloudEvents binary mode metadata, requests follow the CloudEvents HTTP binding.
+// Requests are sent concurrently, so ordering keys are not respected.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// ErrClosed is returned when publishing messages to a closed Driver.
+var ErrClosed = errors.New("webhook: driver is closed")
+
+// StatusError is returned when an endpoint responds with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook: unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retryable reports whether the request should be retried.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+var (
+	// ErrInvalidSignature is returned from Verify when the signature does not match.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrTimestampOutOfTolerance is returned from Verify when the timestamp is too old or too new.
+	ErrTimestampOutOfTolerance = errors.New("webhook: timestamp is out of tolerance")
+)
+
+// Sign returns the HMAC-SHA256 signature of "<timestamp>.<body>" as "sha256=<hex>".
+// timestamp is the Unix time in seconds sent with the timestamp header.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that signature is a valid signature of body and timestamp,
+// and that timestamp is within tolerance from now, so that captured requests cannot be replayed later.
+func Verify(secret []byte, timestamp string, body []byte, signature string, tolerance time.Duration) error {
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrTimestampOutOfTolerance
+	}
+	if diff := time.Since(time.Unix(sec, 0)); diff > tolerance || diff < -tolerance {
+		return ErrTimestampOutOfTolerance
+	}
+
+	return nil
+}
+
+type Driver struct {
+	defaultURL string
+	cfg        *Config
+
+	sem chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+var _ pubee.Driver = (*Driver)(nil)
+
+// NewDriver creates a Driver that POSTs messages to url by default, and to URLs specified by pubee.WithTopic.
+func NewDriver(url string, opts ...Option) *Driver {
+	cfg := newConfig(opts)
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+
+	return &Driver{
+		defaultURL: url,
+		cfg:        cfg,
+		sem:        make(chan struct{}, cfg.MaxConcurrency),
+	}
+}
+
+func (d *Driver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	errCh := make(chan error, 1)
+
+	url := msg.Topic
+	if url == "" {
+		url = d.defaultURL
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	switch {
+	case d.closed:
+		errCh <- ErrClosed
+	case url == "":
+		errCh <- errors.New("webhook: url is not specified")
+	default:
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			defer close(errCh)
+			if err := d.send(ctx, url, msg); err != nil {
+				errCh <- err
+			}
+		}()
+		return errCh
+	}
+
+	close(errCh)
+	return errCh
+}
+
+// Flush waits for all in-flight requests.
+func (d *Driver) Flush() {
+	d.wg.Wait()
+}
+
+func (d *Driver) Close(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Driver) send(ctx context.Context, url string, msg *pubee.Message) error {
+	select {
+	case d.sem <- struct{}{}:
+		defer func() { <-d.sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	backoff := d.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := d.post(ctx, url, msg)
+		if err == nil || attempt >= d.cfg.MaxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+}
+
+func (d *Driver) post(ctx context.Context, url string, msg *pubee.Message) error {
+	if d.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.cfg.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(msg.Data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	for k, v := range msg.Metadata {
+		req.Header.Set(k, v)
+	}
+	if d.cfg.Secret != nil {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(d.cfg.TimestampHeader, ts)
+		req.Header.Set(d.cfg.SignatureHeader, Sign(d.cfg.Secret, ts, msg.Data))
+	}
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// isRetryable reports whether err is a network error or a retryable status.
+func isRetryable(err error) bool {
+	if e, ok := err.(*StatusError); ok {
+		return e.Retryable()
+	}
+	return !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,234 @@
+package webhook_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/webhook"
+)
+
+func TestDriver(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		bodies  []string
+		headers []http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		headers = append(headers, r.Header)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	secret := []byte("awesomesecret")
+
+	driver := webhook.NewDriver(srv.URL, webhook.WithSecret(secret))
+	publisher := pubee.New(driver, pubee.WithMetadata("X-Foo", "bar"))
+
+	if _, err := publisher.Publish(ctx, "test message").Get(ctx); err != nil {
+		t.Errorf("Publish() returned %v", err)
+	}
+
+	publisher.Close(ctx)
+
+	if got, want := len(bodies), 1; got != want {
+		t.Fatalf("Received %d requests, want %d", got, want)
+	}
+	if got, want := bodies[0], "test message"; got != want {
+		t.Errorf("Request has body %q, want %q", got, want)
+	}
+	if got, want := headers[0].Get("X-Foo"), "bar"; got != want {
+		t.Errorf("Request has header X-Foo=%q, want %q", got, want)
+	}
+	if got, want := headers[0].Get("Content-Type"), "text/plain; charset=utf-8"; got != want {
+		t.Errorf("Request has header Content-Type=%q, want %q", got, want)
+	}
+	ts, sig := headers[0].Get(webhook.DefaultTimestampHeader), headers[0].Get(webhook.DefaultSignatureHeader)
+	if err := webhook.Verify(secret, ts, []byte(bodies[0]), sig, webhook.DefaultTolerance); err != nil {
+		t.Errorf("Request has invalid signature %q (timestamp: %q): %v", sig, ts, err)
+	}
+
+	if got, want := <-driver.Publish(ctx, &pubee.Message{}), webhook.ErrClosed; got != want {
+		t.Errorf("Publish() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("awesomesecret")
+	body := []byte("test message")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	cases := []struct {
+		test      string
+		timestamp string
+		body      []byte
+		signature string
+		wantErr   error
+	}{
+		{
+			test:      "valid",
+			timestamp: now,
+			body:      body,
+			signature: webhook.Sign(secret, now, body),
+		},
+		{
+			test:      "tampered body",
+			timestamp: now,
+			body:      []byte("tampered message"),
+			signature: webhook.Sign(secret, now, body),
+			wantErr:   webhook.ErrInvalidSignature,
+		},
+		{
+			test:      "tampered timestamp",
+			timestamp: now,
+			body:      body,
+			signature: webhook.Sign(secret, old, body),
+			wantErr:   webhook.ErrInvalidSignature,
+		},
+		{
+			test:      "replayed",
+			timestamp: old,
+			body:      body,
+			signature: webhook.Sign(secret, old, body),
+			wantErr:   webhook.ErrTimestampOutOfTolerance,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			err := webhook.Verify(secret, tc.timestamp, tc.body, tc.signature, webhook.DefaultTolerance)
+			if got, want := err, tc.wantErr; got != want {
+				t.Errorf("Verify() returned %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDriver_Retry(t *testing.T) {
+	cases := []struct {
+		test     string
+		statuses []int
+		wantErr  bool
+		wantReqs int32
+	}{
+		{
+			test:     "succeed after 5xx",
+			statuses: []int{503, 500, 200},
+			wantReqs: 3,
+		},
+		{
+			test:     "exhaust attempts",
+			statuses: []int{503, 503, 503, 200},
+			wantErr:  true,
+			wantReqs: 3,
+		},
+		{
+			test:     "do not retry on 4xx",
+			statuses: []int{400, 200},
+			wantErr:  true,
+			wantReqs: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var n int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := atomic.AddInt32(&n, 1) - 1
+				w.WriteHeader(tc.statuses[i])
+			}))
+			defer srv.Close()
+
+			ctx := context.Background()
+
+			driver := webhook.NewDriver(srv.URL,
+				webhook.WithMaxAttempts(3),
+				webhook.WithBackoff(time.Millisecond, time.Millisecond),
+			)
+			defer driver.Close(ctx)
+
+			err := <-driver.Publish(ctx, &pubee.Message{Data: []byte("test message")})
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("Publish() returned %v, want error: %t", err, want)
+			}
+			if got, want := atomic.LoadInt32(&n), tc.wantReqs; got != want {
+				t.Errorf("Received %d requests, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestDriver_WithTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	ctx := context.Background()
+
+	driver := webhook.NewDriver(srv.URL,
+		webhook.WithTimeout(10*time.Millisecond),
+		webhook.WithMaxAttempts(1),
+	)
+	defer driver.Close(ctx)
+
+	if err := <-driver.Publish(ctx, &pubee.Message{}); err == nil {
+		t.Error("Publish() should return an error")
+	}
+}
+
+func TestDriver_WithMaxConcurrency(t *testing.T) {
+	var cur, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		defer atomic.AddInt32(&cur, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	driver := webhook.NewDriver(srv.URL, webhook.WithMaxConcurrency(2))
+
+	var errChs []<-chan error
+	for i := 0; i < 10; i++ {
+		errChs = append(errChs, driver.Publish(ctx, &pubee.Message{}))
+	}
+	driver.Flush()
+
+	for _, errCh := range errChs {
+		if err := <-errCh; err != nil {
+			t.Errorf("Publish() returned %v", err)
+		}
+	}
+	if got, want := atomic.LoadInt32(&max), int32(2); got > want {
+		t.Errorf("Max concurrent requests were %d, want at most %d", got, want)
+	}
+
+	if err := driver.Close(ctx); err != nil {
+		t.Errorf("Close() returned %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultSignatureHeader is the header name HMAC signatures are set to.
+	DefaultSignatureHeader = "X-Pubee-Signature"
+	// DefaultTimestampHeader is the header name signed timestamps are set to.
+	DefaultTimestampHeader = "X-Pubee-Timestamp"
+	// DefaultTolerance is the recommended tolerance of timestamps for Verify.
+	DefaultTolerance = 5 * time.Minute
+)
+
+// Config represents driver configuration.
+type Config struct {
+	Client          *http.Client
+	Timeout         time.Duration
+	Secret          []byte
+	SignatureHeader string
+	TimestampHeader string
+	MaxAttempts     int
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+	MaxConcurrency  int
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		Client:          http.DefaultClient,
+		Timeout:         10 * time.Second,
+		SignatureHeader: DefaultSignatureHeader,
+		TimestampHeader: DefaultTimestampHeader,
+		MaxAttempts:     3,
+		InitialBackoff:  100 * time.Millisecond,
+		MaxBackoff:      5 * time.Second,
+		MaxConcurrency:  10,
+	}
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is driver Option
+type Option func(*Config)
+
+// WithClient returns an Option that set an HTTP client used for requests.
+func WithClient(c *http.Client) Option {
+	return func(cfg *Config) {
+		cfg.Client = c
+	}
+}
+
+// WithTimeout returns an Option that set the time limit for each request.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = d
+	}
+}
+
+// WithSecret returns an Option that signs request bodies with HMAC-SHA256.
+// The Unix time of each request is set to the X-Pubee-Timestamp header, and signed with the body.
+// Signatures are set to the X-Pubee-Signature header as "sha256=<hex>". Receivers should check them with Verify.
+func WithSecret(secret []byte) Option {
+	return func(c *Config) {
+		c.Secret = secret
+	}
+}
+
+// WithSignatureHeader returns an Option that set the header name signatures are set to.
+func WithSignatureHeader(name string) Option {
+	return func(c *Config) {
+		c.SignatureHeader = name
+	}
+}
+
+// WithTimestampHeader returns an Option that set the header name signed timestamps are set to.
+func WithTimestampHeader(name string) Option {
+	return func(c *Config) {
+		c.TimestampHeader = name
+	}
+}
+
+// WithMaxAttempts returns an Option that set the maximum number of attempts per message.
+// Requests failed with network errors, 429 or 5xx responses are retried with exponential backoff.
+func WithMaxAttempts(n int) Option {
+	return func(c *Config) {
+		c.MaxAttempts = n
+	}
+}
+
+// WithBackoff returns an Option that set the initial and the maximum wait between attempts.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(c *Config) {
+		c.InitialBackoff = initial
+		c.MaxBackoff = max
+	}
+}
+
+// WithMaxConcurrency returns an Option that set the maximum number of in-flight requests.
+func WithMaxConcurrency(n int) Option {
+	return func(c *Config) {
+		c.MaxConcurrency = n
+	}
+}
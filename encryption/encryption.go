@@ -0,0 +1,308 @@
+// Package encryption encrypts message data with AES-GCM envelope encryption.
+//
+// Each message is encrypted with a data key, and the data key wrapped by a key encryption key of a KeyProvider
+// is stored into the metadata with the key ID and the algorithm. Consumers unwrap the data key with the KeyProvider
+// and decrypt the data with Decrypt or SubscribeInterceptor.
+// Metadata present at encryption are authenticated with the data, so tampering with them fails decryption.
+// Metadata added afterward, e.g. by drivers, are not authenticated.
+// To compress messages, compress them before encryption.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+const (
+	// Algorithm is the name of the encryption algorithm.
+	Algorithm = "AES-256-GCM"
+
+	// AlgorithmKey is the metadata key of the encryption algorithm.
+	AlgorithmKey = "encryption-algorithm"
+	// KeyIDKey is the metadata key of the key encryption key ID.
+	KeyIDKey = "encryption-key-id"
+	// DataKeyKey is the metadata key of the wrapped data key encoded in base64.
+	DataKeyKey = "encryption-data-key"
+	// MetadataKeysKey is the metadata key of the authenticated metadata keys encoded in a JSON array.
+	MetadataKeysKey = "encryption-metadata-keys"
+)
+
+var (
+	// ErrUnsupportedAlgorithm is returned when decrypting data encrypted with an unknown algorithm.
+	ErrUnsupportedAlgorithm = errors.New("encryption: unsupported algorithm")
+	// ErrNotEncrypted is returned when decrypting plain data with WithRequireEncryption,
+	// and when publishing a message that has the encryption metadata but cannot be decrypted with them.
+	ErrNotEncrypted = errors.New("encryption: message is not encrypted")
+)
+
+// WithEncryption returns a pubee.Option that encrypts message data with data keys from the KeyProvider.
+func WithEncryption(p KeyProvider, opts ...Option) pubee.Option {
+	return pubee.WithPublishInterceptors(PublishInterceptor(p, opts...))
+}
+
+// PublishInterceptor returns a pubee.PublishInterceptor that encrypts message data with data keys from the KeyProvider.
+// Messages already encrypted with the KeyProvider are published as is,
+// and messages having the encryption metadata but not decrypted with them fail with ErrNotEncrypted.
+func PublishInterceptor(p KeyProvider, opts ...Option) pubee.PublishInterceptor {
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	keys := &dataKeyCache{provider: p, maxAge: cfg.DataKeyMaxAge}
+
+	return func(ctx context.Context, msg *pubee.Message, h pubee.PublishHandler) <-chan error {
+		if _, ok := msg.Metadata[AlgorithmKey]; ok {
+			// the metadata may be set by users or copied from received messages, so data are verified not to publish plain text.
+			if err := verify(ctx, p, msg.Data, msg.Metadata); err != nil {
+				errCh := make(chan error, 1)
+				errCh <- err
+				close(errCh)
+				return errCh
+			}
+			return h(ctx, msg)
+		}
+
+		data, md, err := encrypt(ctx, keys, msg.Data, msg.Metadata)
+		if err != nil {
+			errCh := make(chan error, 1)
+			errCh <- err
+			close(errCh)
+			return errCh
+		}
+
+		msg.Data = data
+		msg.Metadata = md
+
+		return h(ctx, msg)
+	}
+}
+
+func encrypt(ctx context.Context, keys *dataKeyCache, data []byte, metadata map[string]string) ([]byte, map[string]string, error) {
+	key, err := keys.get(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encryption: failed to generate a data key: %w", err)
+	}
+
+	md := make(map[string]string, len(metadata)+4)
+	mdKeys := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		md[k] = v
+		mdKeys = append(mdKeys, k)
+	}
+	sort.Strings(mdKeys)
+	encodedKeys, err := json.Marshal(mdKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	md[MetadataKeysKey] = string(encodedKeys)
+
+	ad, err := additionalData(md)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err := seal(key.Plaintext, data, ad)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	md[AlgorithmKey] = Algorithm
+	md[KeyIDKey] = key.KeyID
+	md[DataKeyKey] = base64.StdEncoding.EncodeToString(key.Ciphertext)
+
+	return ciphertext, md, nil
+}
+
+// verify checks that data are encrypted with the metadata by decrypting them.
+func verify(ctx context.Context, p KeyProvider, data []byte, metadata map[string]string) error {
+	for _, k := range []string{AlgorithmKey, KeyIDKey, DataKeyKey, MetadataKeysKey} {
+		if _, ok := metadata[k]; !ok {
+			return fmt.Errorf("%w: metadata %q is missing", ErrNotEncrypted, k)
+		}
+	}
+	if _, err := Decrypt(ctx, p, data, metadata); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotEncrypted, err)
+	}
+	return nil
+}
+
+// Decrypt decrypts data with the data key in the metadata.
+// Data are returned as is when the metadata has no encryption algorithm, unless WithRequireEncryption is given.
+func Decrypt(ctx context.Context, p KeyProvider, data []byte, metadata map[string]string, opts ...Option) ([]byte, error) {
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	alg, ok := metadata[AlgorithmKey]
+	if !ok {
+		if cfg.RequireEncryption {
+			return nil, ErrNotEncrypted
+		}
+		return data, nil
+	}
+	if alg != Algorithm {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[DataKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("encryption: invalid data key: %w", err)
+	}
+
+	key, err := p.DecryptDataKey(ctx, metadata[KeyIDKey], wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed to decrypt a data key: %w", err)
+	}
+
+	ad, err := additionalData(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(key, data, ad)
+}
+
+// DecryptMessage decrypts the message data in place, and removes the encryption metadata.
+func DecryptMessage(ctx context.Context, p KeyProvider, m *pubee.ReceivedMessage, opts ...Option) error {
+	data, err := Decrypt(ctx, p, m.Data, m.Metadata, opts...)
+	if err != nil {
+		return err
+	}
+	if _, ok := m.Metadata[AlgorithmKey]; !ok {
+		return nil
+	}
+
+	md := make(map[string]string, len(m.Metadata))
+	for k, v := range m.Metadata {
+		switch k {
+		case AlgorithmKey, KeyIDKey, DataKeyKey, MetadataKeysKey:
+		default:
+			md[k] = v
+		}
+	}
+
+	m.Data = data
+	m.Metadata = md
+
+	return nil
+}
+
+// SubscribeInterceptor returns a pubee.SubscribeInterceptor that decrypts received messages before handlers.
+func SubscribeInterceptor(p KeyProvider, opts ...Option) pubee.SubscribeInterceptor {
+	return func(ctx context.Context, m *pubee.ReceivedMessage, h pubee.Handler) error {
+		if err := DecryptMessage(ctx, p, m, opts...); err != nil {
+			return err
+		}
+		return h(ctx, m)
+	}
+}
+
+// dataKeyCache reuses a data key within maxAge.
+type dataKeyCache struct {
+	provider KeyProvider
+	maxAge   time.Duration
+
+	mu        sync.Mutex
+	key       *DataKey
+	createdAt time.Time
+}
+
+func (c *dataKeyCache) get(ctx context.Context) (*DataKey, error) {
+	if c.maxAge <= 0 {
+		return c.provider.GenerateDataKey(ctx)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.key != nil && time.Since(c.createdAt) < c.maxAge {
+		return c.key, nil
+	}
+
+	key, err := c.provider.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.key, c.createdAt = key, time.Now()
+
+	return key, nil
+}
+
+// seal encrypts plaintext with AES-GCM, and prepends a random nonce to the ciphertext.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("encryption: ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// additionalData encodes the metadata listed in MetadataKeysKey with the list itself, to authenticate them with GCM.
+// Messages encrypted without the list have no additional data.
+func additionalData(metadata map[string]string) ([]byte, error) {
+	encodedKeys, ok := metadata[MetadataKeysKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var keys []string
+	if err := json.Unmarshal([]byte(encodedKeys), &keys); err != nil {
+		return nil, fmt.Errorf("encryption: invalid metadata keys: %w", err)
+	}
+
+	// [keys, value1, value2, ...]
+	ad := make([]interface{}, 0, len(keys)+1)
+	ad = append(ad, keys)
+	for _, k := range keys {
+		v, ok := metadata[k]
+		if !ok {
+			return nil, fmt.Errorf("encryption: authenticated metadata %q is missing", k)
+		}
+		ad = append(ad, v)
+	}
+
+	return json.Marshal(ad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
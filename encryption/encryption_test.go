@@ -0,0 +1,292 @@
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+	"github.com/izumin5210/pubee/encryption"
+)
+
+type Document struct {
+	Body string `json:"body"`
+}
+
+func newKeyring(t *testing.T, ids ...string) *encryption.Keyring {
+	t.Helper()
+
+	r := encryption.NewKeyring()
+	for i, id := range ids {
+		if err := r.Add(id, bytes.Repeat([]byte{byte(i + 1)}, 32)); err != nil {
+			t.Fatalf("Add() returned %v", err)
+		}
+	}
+	return r
+}
+
+func TestWithEncryption(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(keyring), pubee.WithMetadata("foo", "bar"))
+
+	doc := &Document{Body: "secret"}
+	publisher.Publish(ctx, doc)
+	publisher.Publish(ctx, doc)
+	publisher.Close(ctx)
+
+	msgs := driver.Messages()
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Published %d messages, want %d", got, want)
+	}
+	if bytes.Contains(msgs[0].Data, []byte("secret")) {
+		t.Error("Published message should be encrypted")
+	}
+	if got, want := msgs[0].Metadata[encryption.AlgorithmKey], encryption.Algorithm; got != want {
+		t.Errorf("Published message has algorithm %q, want %q", got, want)
+	}
+	if got, want := msgs[0].Metadata[encryption.KeyIDKey], "key-1"; got != want {
+		t.Errorf("Published message has key ID %q, want %q", got, want)
+	}
+	if msgs[0].Metadata[encryption.DataKeyKey] == msgs[1].Metadata[encryption.DataKeyKey] {
+		t.Error("Each message should be encrypted with a different data key")
+	}
+
+	m := &pubee.ReceivedMessage{Data: msgs[0].Data, Metadata: msgs[0].Metadata}
+	if err := encryption.DecryptMessage(ctx, keyring, m); err != nil {
+		t.Fatalf("DecryptMessage() returned %v", err)
+	}
+	var got Document
+	if err := m.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal() returned %v", err)
+	}
+	if got.Body != doc.Body {
+		t.Errorf("Decrypted message has body %q, want %q", got.Body, doc.Body)
+	}
+	if _, ok := m.Metadata[encryption.DataKeyKey]; ok {
+		t.Error("Decrypted message should not have a data key")
+	}
+	if got, want := m.Metadata["foo"], "bar"; got != want {
+		t.Errorf("Decrypted message has metadata %q, want %q", got, want)
+	}
+}
+
+func TestWithEncryption_Rotation(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(keyring))
+
+	if _, err := publisher.Publish(ctx, "old").Get(ctx); err != nil {
+		t.Fatalf("Publish() returned %v", err)
+	}
+	if err := keyring.Add("key-2", bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatalf("Add() returned %v", err)
+	}
+	publisher.Publish(ctx, "new")
+	publisher.Close(ctx)
+
+	msgs := driver.Messages()
+	for i, want := range []string{"key-1", "key-2"} {
+		if got := msgs[i].Metadata[encryption.KeyIDKey]; got != want {
+			t.Errorf("Message %d has key ID %q, want %q", i, got, want)
+		}
+		data, err := encryption.Decrypt(ctx, keyring, msgs[i].Data, msgs[i].Metadata)
+		if err != nil {
+			t.Errorf("Decrypt() returned %v", err)
+		}
+		if len(data) == 0 {
+			t.Errorf("Message %d is decrypted to empty data", i)
+		}
+	}
+
+	keyring.Remove("key-1")
+	_, err := encryption.Decrypt(ctx, keyring, msgs[0].Data, msgs[0].Metadata)
+	if !errors.Is(err, encryption.ErrKeyNotFound) {
+		t.Errorf("Decrypt() returned %v, want %v", err, encryption.ErrKeyNotFound)
+	}
+}
+
+func TestWithEncryption_DataKeyMaxAge(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(newKeyring(t, "key-1"), encryption.WithDataKeyMaxAge(time.Minute)))
+
+	publisher.Publish(ctx, "foo")
+	publisher.Publish(ctx, "bar")
+	publisher.Close(ctx)
+
+	msgs := driver.Messages()
+	if msgs[0].Metadata[encryption.DataKeyKey] != msgs[1].Metadata[encryption.DataKeyKey] {
+		t.Error("Messages should be encrypted with the same data key")
+	}
+	if bytes.Equal(msgs[0].Data, msgs[1].Data) {
+		t.Error("Messages should be encrypted with different nonces")
+	}
+}
+
+func TestWithEncryption_WithoutKey(t *testing.T) {
+	ctx := context.Background()
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(encryption.NewKeyring()))
+	defer publisher.Close(ctx)
+
+	if _, err := publisher.Publish(ctx, "foo").Get(ctx); !errors.Is(err, encryption.ErrKeyNotFound) {
+		t.Errorf("Publish() returned %v, want %v", err, encryption.ErrKeyNotFound)
+	}
+	if got, want := len(driver.Messages()), 0; got != want {
+		t.Errorf("Published %d messages, want %d", got, want)
+	}
+}
+
+func TestSubscribeInterceptor(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(keyring))
+	publisher.Publish(ctx, "foo")
+	publisher.Close(ctx)
+
+	msg := driver.Messages()[0]
+	tampered := append([]byte(nil), msg.Data...)
+	tampered[len(tampered)-1] ^= 1
+
+	var got []string
+	h := func(_ context.Context, m *pubee.ReceivedMessage) error {
+		got = append(got, string(m.Data))
+		return nil
+	}
+	interceptor := encryption.SubscribeInterceptor(keyring)
+
+	if err := interceptor(ctx, &pubee.ReceivedMessage{Data: msg.Data, Metadata: msg.Metadata}, h); err != nil {
+		t.Errorf("interceptor returned %v", err)
+	}
+	if err := interceptor(ctx, &pubee.ReceivedMessage{Data: []byte("plain")}, h); err != nil {
+		t.Errorf("interceptor returned %v for a plain message", err)
+	}
+	if err := interceptor(ctx, &pubee.ReceivedMessage{Data: tampered, Metadata: msg.Metadata}, h); err == nil {
+		t.Error("interceptor should return an error for a tampered message")
+	}
+	if got, want := len(got), 2; got != want {
+		t.Errorf("Handled %d messages, want %d", got, want)
+	}
+}
+
+func TestWithEncryption_WhenEncrypted(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(keyring), encryption.WithEncryption(keyring))
+
+	publisher.Publish(ctx, "foo")
+	publisher.Close(ctx)
+
+	msg := driver.Messages()[0]
+	data, err := encryption.Decrypt(ctx, keyring, msg.Data, msg.Metadata)
+	if err != nil {
+		t.Fatalf("Decrypt() returned %v", err)
+	}
+	if got, want := string(data), "foo"; got != want {
+		t.Errorf("Decrypted message has data %q, want %q", got, want)
+	}
+}
+
+func TestWithEncryption_WhenNotEncrypted(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+
+	cases := []struct {
+		test     string
+		metadata map[string]string
+	}{
+		{
+			test:     "algorithm only",
+			metadata: map[string]string{encryption.AlgorithmKey: encryption.Algorithm},
+		},
+		{
+			test: "all metadata",
+			metadata: map[string]string{
+				encryption.AlgorithmKey:    encryption.Algorithm,
+				encryption.KeyIDKey:        "key-1",
+				encryption.DataKeyKey:      "AAAA",
+				encryption.MetadataKeysKey: "[]",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			driver := memory.NewDriver()
+			var opts []pubee.Option
+			for k, v := range tc.metadata {
+				opts = append(opts, pubee.WithMetadata(k, v))
+			}
+			publisher := pubee.New(driver, append(opts, encryption.WithEncryption(keyring))...)
+			defer publisher.Close(ctx)
+
+			if _, err := publisher.Publish(ctx, "secret").Get(ctx); !errors.Is(err, encryption.ErrNotEncrypted) {
+				t.Errorf("Publish() returned %v, want %v", err, encryption.ErrNotEncrypted)
+			}
+			if got, want := len(driver.Messages()), 0; got != want {
+				t.Errorf("Published %d messages, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestDecrypt_WithRequireEncryption(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+
+	_, err := encryption.Decrypt(ctx, keyring, []byte("plain"), map[string]string{"foo": "bar"}, encryption.WithRequireEncryption())
+	if !errors.Is(err, encryption.ErrNotEncrypted) {
+		t.Errorf("Decrypt() returned %v, want %v", err, encryption.ErrNotEncrypted)
+	}
+
+	m := &pubee.ReceivedMessage{Data: []byte("plain")}
+	if err := encryption.DecryptMessage(ctx, keyring, m, encryption.WithRequireEncryption()); !errors.Is(err, encryption.ErrNotEncrypted) {
+		t.Errorf("DecryptMessage() returned %v, want %v", err, encryption.ErrNotEncrypted)
+	}
+}
+
+func TestDecrypt_WhenMetadataTampered(t *testing.T) {
+	ctx := context.Background()
+	keyring := newKeyring(t, "key-1")
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, encryption.WithEncryption(keyring), pubee.WithMetadata("foo", "bar"))
+	publisher.Publish(ctx, "foo")
+	publisher.Close(ctx)
+
+	msg := driver.Messages()[0]
+
+	cases := []struct {
+		test   string
+		modify func(md map[string]string)
+		ok     bool
+	}{
+		{test: "added", modify: func(md map[string]string) { md["baz"] = "qux" }, ok: true},
+		{test: "changed", modify: func(md map[string]string) { md["foo"] = "baz" }},
+		{test: "removed", modify: func(md map[string]string) { delete(md, "foo") }},
+		{test: "keys removed", modify: func(md map[string]string) { delete(md, encryption.MetadataKeysKey) }},
+		{test: "keys changed", modify: func(md map[string]string) { md[encryption.MetadataKeysKey] = "[]" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			md := make(map[string]string, len(msg.Metadata))
+			for k, v := range msg.Metadata {
+				md[k] = v
+			}
+			tc.modify(md)
+
+			_, err := encryption.Decrypt(ctx, keyring, msg.Data, md)
+			if got, want := err == nil, tc.ok; got != want {
+				t.Errorf("Decrypt() returned %v, want success: %t", err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DataKey is a key encrypting message data.
+type DataKey struct {
+	// KeyID identifies the key encryption key that wrapped the data key.
+	KeyID string
+	// Plaintext is the data key. It should not be stored anywhere.
+	Plaintext []byte
+	// Ciphertext is the data key wrapped by the key encryption key, which is stored into message metadata.
+	Ciphertext []byte
+}
+
+// KeyProvider generates and unwraps data keys with key encryption keys, like a KMS.
+type KeyProvider interface {
+	// GenerateDataKey returns a new 256-bit data key wrapped by the current key encryption key.
+	GenerateDataKey(ctx context.Context) (*DataKey, error)
+	// DecryptDataKey unwraps a data key wrapped by the key encryption key of keyID.
+	DecryptDataKey(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// ErrKeyNotFound is returned when a key encryption key is not found.
+var ErrKeyNotFound = errors.New("encryption: key not found")
+
+// Keyring is a KeyProvider holding key encryption keys in memory.
+// It is useful for tests and local development.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	primary string
+}
+
+var _ KeyProvider = (*Keyring)(nil)
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string][]byte{}}
+}
+
+// Add adds a 256-bit key encryption key, and makes it primary.
+// Previous keys are kept to decrypt data keys wrapped by them, so that keys can be rotated.
+func (r *Keyring) Add(keyID string, key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("encryption: key should be 32 bytes, but %d bytes", len(key))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[keyID] = append([]byte(nil), key...)
+	r.primary = keyID
+
+	return nil
+}
+
+// Remove removes a key encryption key. Data keys wrapped by it cannot be unwrapped anymore.
+func (r *Keyring) Remove(keyID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.keys, keyID)
+	if r.primary == keyID {
+		r.primary = ""
+	}
+}
+
+func (r *Keyring) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	r.mu.RLock()
+	keyID, kek := r.primary, r.keys[r.primary]
+	r.mu.RUnlock()
+
+	if kek == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(kek, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{KeyID: keyID, Plaintext: key, Ciphertext: ciphertext}, nil
+}
+
+func (r *Keyring) DecryptDataKey(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	r.mu.RLock()
+	kek := r.keys[keyID]
+	r.mu.RUnlock()
+
+	if kek == nil {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+
+	return open(kek, ciphertext, nil)
+}
@@ -0,0 +1,34 @@
+package encryption
+
+import "time"
+
+// Config represents encryption configuration.
+type Config struct {
+	DataKeyMaxAge     time.Duration
+	RequireEncryption bool
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is encryption Option
+type Option func(*Config)
+
+// WithDataKeyMaxAge returns an Option that reuses a data key for messages published within d.
+// It reduces calls to the KeyProvider. A data key is generated per message by default.
+func WithDataKeyMaxAge(d time.Duration) Option {
+	return func(c *Config) {
+		c.DataKeyMaxAge = d
+	}
+}
+
+// WithRequireEncryption returns an Option that fails decrypting plain messages with ErrNotEncrypted,
+// so that consumers reject messages published without encryption.
+func WithRequireEncryption() Option {
+	return func(c *Config) {
+		c.RequireEncryption = true
+	}
+}
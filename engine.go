@@ -2,24 +2,56 @@ package pubee
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/izumin5210/pubee/marshal"
+	"github.com/izumin5210/pubee/codec"
 )
 
+// ErrClosed is returned when publishing messages with a closed Engine.
+var ErrClosed = errors.New("pubee: engine is closed")
+
+// AbandonedError is returned from Engine.Close when the context is done before all in-flight messages are published.
+type AbandonedError struct {
+	// Count is the number of messages whose results were not ready.
+	Count int
+	Err   error
+}
+
+func (e *AbandonedError) Error() string {
+	return fmt.Sprintf("pubee: %d messages were abandoned: %v", e.Count, e.Err)
+}
+
+func (e *AbandonedError) Unwrap() error { return e.Err }
+
 type Engine interface {
-	Publish(context.Context, interface{}, ...PublishOption)
+	Publish(context.Context, interface{}, ...PublishOption) *PublishResult
+	// Close stops accepting messages, and waits for in-flight messages until the context is done.
+	// It returns an *AbandonedError when the context is done first.
 	Close(context.Context) error
+	// InFlight returns the number and the total data size of messages whose results are not ready.
+	InFlight() (messages, bytes int)
 }
 
 type Message struct {
-	Data     []byte
-	Metadata map[string]string
-	Original interface{}
+	// ID is the message ID assigned by the message broker.
+	// Drivers should set it before the error channel returned from Driver.Publish is closed.
+	ID string
+	// Topic is the name of the topic the message is published to.
+	// Drivers should publish the message to their default topic when it is empty.
+	Topic string
+	// OrderingKey identifies messages that should be delivered in the order they are published.
+	// Drivers map it to the ordering concept of their brokers, such as an ordering key on Cloud Pub/Sub
+	// or a record (partition) key on Kafka. Drivers that cannot keep the order should ignore it.
+	OrderingKey string
+	Data        []byte
+	Metadata    map[string]string
+	Original    interface{}
 }
 
-type Interceptor func(context.Context, *Message, func(context.Context, *Message))
-
 func New(d Driver, opts ...Option) Engine {
 	cfg := new(Config)
 	cfg.ErrorLog = defaultErrorLog
@@ -27,16 +59,28 @@ func New(d Driver, opts ...Option) Engine {
 	return &engineImpl{
 		driver: d,
 		cfg:    cfg,
+		flow:   newFlowController(cfg.FlowControl),
 	}
 }
 
 type engineImpl struct {
+	pending int64 // accessed atomically, and first for 64-bit alignment
+
 	driver Driver
 	cfg    *Config
+	flow   *flowController
 	wg     sync.WaitGroup
+	// calls tracks Publish calls until they hand messages to the driver, so that Close flushes the driver after them.
+	calls sync.WaitGroup
+	// retries tracks messages published with retries until the last attempt, so that Close flushes the driver after them.
+	// Drivers stopped by Flush, such as Cloud Pub/Sub, cannot publish retried messages after it.
+	retries sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
 }
 
-func (p *engineImpl) Publish(ctx context.Context, body interface{}, opts ...PublishOption) {
+func (p *engineImpl) Publish(ctx context.Context, body interface{}, opts ...PublishOption) *PublishResult {
 	cfg := new(PublishConfig)
 	cfg.apply(p.cfg.PublishOpts)
 	cfg.apply(opts)
@@ -45,42 +89,126 @@ func (p *engineImpl) Publish(ctx context.Context, body interface{}, opts ...Publ
 		ctx = setErrorLog(ctx, l)
 	}
 
-	if cfg.Marshal == nil {
-		cfg.Marshal = marshal.Default
+	res := newPublishResult()
+
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		res.set("", ErrClosed)
+		return res
+	}
+	p.wg.Add(1)
+	p.calls.Add(1)
+	atomic.AddInt64(&p.pending, 1)
+	p.mu.RUnlock()
+	defer p.calls.Done()
+
+	c := cfg.Codec
+	if c == nil {
+		c = codec.Default(body)
 	}
 
 	var errCh <-chan error
-	msg := &Message{Metadata: cfg.Metadata, Original: body}
+	msg := &Message{Topic: cfg.Topic, OrderingKey: cfg.OrderingKey, Metadata: copyMetadata(cfg.Metadata), Original: body}
 
-	data, err := cfg.Marshal(body)
+	if ct := c.ContentType(); ct != "" {
+		if _, ok := msg.Metadata[codec.ContentTypeKey]; !ok {
+			if msg.Metadata == nil {
+				msg.Metadata = map[string]string{}
+			}
+			msg.Metadata[codec.ContentTypeKey] = ct
+		}
+	}
+
+	data, err := c.Marshal(body)
 	if err != nil {
 		ch := make(chan error, 1)
 		ch <- err
 		errCh = ch
 	}
 
+	var fe *flowEntry
 	if errCh == nil {
 		msg.Data = data
 
+		fe, err = p.flow.acquire(ctx, len(data))
+		if err != nil {
+			ch := make(chan error, 1)
+			ch <- err
+			errCh = ch
+		}
+	}
+
+	// snapshot is the message before interceptors. It is stored as a dead letter so that it can be replayed
+	// through the interceptors again, and reported instead of msg when the message is dropped
+	// because the driver may still use msg.
+	var (
+		snapshot Message
+		attempts int32
+	)
+	published := fe != nil
+	cancel := func() {}
+	if published {
+		snapshot = *msg
+		snapshot.Metadata = copyMetadata(msg.Metadata)
+
+		// ctx is canceled when the result is ready or the message is dropped, so that drivers and retries give it up.
+		ctx, cancel = context.WithCancel(ctx)
+
+		h := p.retryingPublish(&attempts)
 		if f := p.cfg.Interceptor; f == nil {
-			errCh = p.driver.Publish(ctx, msg)
+			errCh = h(ctx, msg)
 		} else {
-			f(ctx, msg, func(ctx context.Context, msg *Message) {
-				errCh = p.driver.Publish(ctx, msg)
-			})
+			errCh = f(ctx, msg, h)
+		}
+
+		// the message was dropped by an interceptor
+		if errCh == nil {
+			ch := make(chan error)
+			close(ch)
+			errCh = ch
 		}
 	}
 
-	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
-		if err := <-errCh; err != nil {
-			GetErrorLog(ctx).Printf("failed to publish message: %v (metadata: %v)", err, msg.Metadata)
+		defer atomic.AddInt64(&p.pending, -1)
+		defer cancel()
+
+		var (
+			err     error
+			dropped bool
+		)
+		if published {
+			select {
+			case err = <-errCh:
+			case <-fe.dropped:
+				err, dropped = ErrMessageDropped, true
+				cancel()
+			}
+			p.flow.release(fe)
+		} else {
+			err = <-errCh
+		}
+
+		m := msg
+		if dropped {
+			m = &snapshot
+		}
+		if err != nil {
+			GetErrorLog(ctx).Printf("failed to publish message: %v (metadata: %v)", err, m.Metadata)
 			if f := p.cfg.OnFailPublishFunc; f != nil {
-				f(msg, err)
+				f(m, err)
+			}
+			// dropped messages may still be delivered by drivers ignoring the cancellation, so they are not replayed.
+			if s := p.cfg.DeadLetterSink; s != nil && published && !dropped {
+				p.putDeadLetter(ctx, s, &DeadLetter{Message: &snapshot, Err: err, Attempts: int(atomic.LoadInt32(&attempts)), FailedAt: time.Now()})
 			}
 		}
+		res.set(m.ID, err)
 	}()
+
+	return res
 }
 
 func (p *engineImpl) Close(ctx context.Context) error {
@@ -88,7 +216,59 @@ func (p *engineImpl) Close(ctx context.Context) error {
 		ctx = setErrorLog(ctx, l)
 	}
 
-	p.driver.Flush()
-	p.wg.Wait()
-	return p.driver.Close(ctx)
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.calls.Wait()
+		p.retries.Wait()
+		p.driver.Flush()
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return p.driver.Close(ctx)
+	case <-ctx.Done():
+		err := &AbandonedError{Count: int(atomic.LoadInt64(&p.pending)), Err: ctx.Err()}
+		GetErrorLog(ctx).Printf("failed to drain messages: %v", err)
+		if cerr := p.driver.Close(ctx); cerr != nil {
+			GetErrorLog(ctx).Printf("failed to close driver: %v", cerr)
+		}
+		return err
+	}
+}
+
+func (p *engineImpl) putDeadLetter(ctx context.Context, s DeadLetterSink, dl *DeadLetter) {
+	defer func() {
+		if r := recover(); r != nil {
+			GetErrorLog(ctx).Printf("failed to put a dead letter: panic: %v (metadata: %v)", r, dl.Message.Metadata)
+		}
+	}()
+
+	if err := s.Put(context.Background(), dl); err != nil {
+		GetErrorLog(ctx).Printf("failed to put a dead letter: %v (metadata: %v)", err, dl.Message.Metadata)
+	}
+}
+
+func (p *engineImpl) InFlight() (messages, bytes int) {
+	return p.flow.inFlight()
+}
+
+func copyMetadata(md map[string]string) map[string]string {
+	if md == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(md))
+	for k, v := range md {
+		copied[k] = v
+	}
+	return copied
 }
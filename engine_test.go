@@ -5,34 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/golang/protobuf/proto/proto3_proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/google/go-cmp/cmp"
 	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+	"google.golang.org/protobuf/testing/protocmp"
 )
 
-type fakeDriver struct {
-	Messages    []*pubee.Message
-	PublishFunc func(context.Context, *pubee.Message) <-chan error
-}
-
-var _ pubee.Driver = (*fakeDriver)(nil)
-
-func (d *fakeDriver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
-	if f := d.PublishFunc; f != nil {
-		return f(ctx, msg)
-	}
-	d.Messages = append(d.Messages, msg)
-	errCh := make(chan error)
-	close(errCh)
-	return errCh
-}
-func (d *fakeDriver) Flush()                      {}
-func (d *fakeDriver) Close(context.Context) error { return nil }
-
 type fakeLogger struct {
 	logs []string
 }
@@ -46,7 +31,7 @@ func (l *fakeLogger) Print(v ...interface{}) {
 }
 
 func TestPublisher_WithMetadata(t *testing.T) {
-	driver := new(fakeDriver)
+	driver := memory.NewDriver()
 	publisher := pubee.New(driver,
 		pubee.WithMetadataMap(map[string]string{"foo": "1", "bar": "2"}),
 		pubee.WithOnFailPublish(func(msg *pubee.Message, err error) {
@@ -58,23 +43,59 @@ func TestPublisher_WithMetadata(t *testing.T) {
 		"foobarbaz",
 		pubee.WithMetadata("baz", "3", "foo", "foooooo"),
 	)
-	if got, want := len(driver.Messages), 1; got != want {
+	if got, want := len(driver.Messages()), 1; got != want {
 		t.Errorf("Published messages are %d, want %d", got, want)
 	} else {
-		msg := driver.Messages[0]
+		msg := driver.Messages()[0]
 		if got, want := string(msg.Data), "foobarbaz"; got != want {
 			t.Errorf("Publish message has data %v, want %v", got, want)
 		}
-		md := map[string]string{"foo": "foooooo", "bar": "2", "baz": "3"}
+		md := map[string]string{"foo": "foooooo", "bar": "2", "baz": "3", "content-type": "text/plain; charset=utf-8"}
 		if got, want := msg.Metadata, md; !reflect.DeepEqual(got, want) {
 			t.Errorf("Publish message has metadata %v, want %v", got, want)
 		}
 	}
 }
 
+func TestPublisher_WithTopic(t *testing.T) {
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver, pubee.WithTopic("awesometopic"))
+	defer publisher.Close(context.Background())
+
+	publisher.Publish(context.Background(), "foo")
+	publisher.Publish(context.Background(), "bar", pubee.WithTopic("othertopic"))
+
+	if got, want := len(driver.TopicMessages("awesometopic")), 1; got != want {
+		t.Errorf("Published messages to awesometopic are %d, want %d", got, want)
+	}
+	if got, want := len(driver.TopicMessages("othertopic")), 1; got != want {
+		t.Errorf("Published messages to othertopic are %d, want %d", got, want)
+	}
+}
+
+func TestPublisher_WithOrderingKey(t *testing.T) {
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver)
+	defer publisher.Close(context.Background())
+
+	publisher.Publish(context.Background(), "foo", pubee.WithOrderingKey("awesomekey"))
+	publisher.Publish(context.Background(), "bar")
+
+	msgs := driver.Messages()
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("Published messages are %d, want %d", got, want)
+	}
+	if got, want := msgs[0].OrderingKey, "awesomekey"; got != want {
+		t.Errorf("Published message has ordering key %q, want %q", got, want)
+	}
+	if got, want := msgs[1].OrderingKey, ""; got != want {
+		t.Errorf("Published message has ordering key %q, want %q", got, want)
+	}
+}
+
 func TestPublisher_WithInterceptors(t *testing.T) {
 	var ops []string
-	driver := new(fakeDriver)
+	driver := memory.NewDriver()
 	publisher := pubee.New(driver,
 		pubee.WithInterceptors(
 			func(ctx context.Context, msg *pubee.Message, handle func(context.Context, *pubee.Message)) {
@@ -102,7 +123,7 @@ func TestPublisher_WithInterceptors(t *testing.T) {
 		"foobarbaz",
 		pubee.WithMetadata("baz", "3", "foo", "foooooo"),
 	)
-	if got, want := len(driver.Messages), 1; got != want {
+	if got, want := len(driver.Messages()), 1; got != want {
 		t.Errorf("Published messages are %d, want %d", got, want)
 	}
 	if got, want := ops, []string{
@@ -113,8 +134,78 @@ func TestPublisher_WithInterceptors(t *testing.T) {
 	}
 }
 
+func TestPublisher_WithPublishInterceptors(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		ops  []string
+		errs []error
+	)
+	appendOp := func(op string) {
+		mu.Lock()
+		defer mu.Unlock()
+		ops = append(ops, op)
+	}
+
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		return errors.New("unfortunate error")
+	})
+	publisher := pubee.New(driver,
+		pubee.WithInterceptors(
+			func(ctx context.Context, msg *pubee.Message, handle func(context.Context, *pubee.Message)) {
+				appendOp("1-before")
+				handle(ctx, msg)
+				appendOp("1-after")
+			},
+		),
+		pubee.WithPublishInterceptors(
+			func(ctx context.Context, msg *pubee.Message, handle pubee.PublishHandler) <-chan error {
+				appendOp("2-before")
+				errCh := handle(ctx, msg)
+				appendOp("2-after")
+
+				outCh := make(chan error, 1)
+				go func() {
+					defer close(outCh)
+					err := <-errCh
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					outCh <- err
+				}()
+				return outCh
+			},
+		),
+		pubee.WithInterceptors(
+			func(ctx context.Context, msg *pubee.Message, handle func(context.Context, *pubee.Message)) {
+				appendOp("3-before")
+				handle(ctx, msg)
+				appendOp("3-after")
+			},
+		),
+	)
+
+	_, err := publisher.Publish(context.Background(), "foobarbaz").Get(context.Background())
+	publisher.Close(context.Background())
+
+	if err == nil {
+		t.Error("PublishResult.Get() should return an error")
+	}
+	if got, want := ops, []string{
+		"1-before", "2-before", "3-before",
+		"3-after", "2-after", "1-after",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("interceptors called order is %v, want %v", got, want)
+	}
+	if got, want := len(errs), 1; got != want {
+		t.Errorf("interceptor observed %d results, want %d", got, want)
+	} else if got, want := errs[0], err; got != want {
+		t.Errorf("interceptor observed %v, want %v", got, want)
+	}
+}
+
 func TestPublisher_WithJSON(t *testing.T) {
-	driver := new(fakeDriver)
+	driver := memory.NewDriver()
 	publisher := pubee.New(driver,
 		pubee.WithJSON(),
 		pubee.WithOnFailPublish(func(msg *pubee.Message, err error) {
@@ -125,52 +216,76 @@ func TestPublisher_WithJSON(t *testing.T) {
 		context.Background(),
 		"foobarbaz",
 	)
-	if got, want := len(driver.Messages), 1; got != want {
+	if got, want := len(driver.Messages()), 1; got != want {
 		t.Errorf("Published messages are %d, want %d", got, want)
 	} else {
-		msg := driver.Messages[0]
+		msg := driver.Messages()[0]
 		if got, want := string(msg.Data), `"foobarbaz"`; got != want {
 			t.Errorf("Publish message has data %v, want %v", got, want)
 		}
+		if got, want := msg.Metadata["content-type"], "application/json"; got != want {
+			t.Errorf("Publish message has content type %v, want %v", got, want)
+		}
 	}
 }
 
 func TestPublisher_WithProtobuf(t *testing.T) {
-	driver := new(fakeDriver)
+	driver := memory.NewDriver()
 	publisher := pubee.New(driver,
 		pubee.WithProtobuf(),
 		pubee.WithOnFailPublish(func(msg *pubee.Message, err error) {
 			t.Errorf("Publish() returns %v, want nil", err)
 		}),
 	)
-	in := &proto3_proto.Message{Name: "Foo Bar", Hilarity: proto3_proto.Message_PUNS}
+	in := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"name":     {Kind: &structpb.Value_StringValue{StringValue: "Foo Bar"}},
+		"hilarity": {Kind: &structpb.Value_NumberValue{NumberValue: 1}},
+	}}
 	publisher.Publish(context.Background(), in)
-	if got, want := len(driver.Messages), 1; got != want {
+	if got, want := len(driver.Messages()), 1; got != want {
 		t.Errorf("Published messages are %d, want %d", got, want)
 	} else {
-		msg := driver.Messages[0]
-		var out proto3_proto.Message
+		msg := driver.Messages()[0]
+		if got, want := msg.Metadata["content-type"], "application/protobuf"; got != want {
+			t.Errorf("Publish message has content type %v, want %v", got, want)
+		}
+		var out structpb.Struct
 		err := proto.Unmarshal(msg.Data, &out)
 		if err != nil {
 			t.Errorf("failed to unmarshal publishhed message: %v", err)
 		}
-		if diff := cmp.Diff(*in, out, cmp.FilterPath(func(p cmp.Path) bool {
-			return strings.HasPrefix(p.Last().String(), ".XXX_")
-		}, cmp.Ignore())); diff != "" {
+		if diff := cmp.Diff(in, &out, protocmp.Transform()); diff != "" {
 			t.Errorf("Publish message mismatch(-want, +got):\n%s", diff)
 		}
 	}
 }
 
-func TestPublisher_OnFailPublish(t *testing.T) {
-	driver := &fakeDriver{
-		PublishFunc: func(ctx context.Context, msg *pubee.Message) <-chan error {
-			ch := make(chan error, 1)
-			ch <- errors.New("unfortunate error")
-			close(ch)
-			return ch
-		},
+func TestPublisher_WithMarshalFunc(t *testing.T) {
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver,
+		pubee.WithMarshalFunc(func(in interface{}) ([]byte, error) { return []byte("marshaled"), nil }),
+	)
+	publisher.Publish(context.Background(), "foobarbaz")
+	publisher.Close(context.Background())
+
+	if got, want := len(driver.Messages()), 1; got != want {
+		t.Errorf("Published messages are %d, want %d", got, want)
+	} else {
+		msg := driver.Messages()[0]
+		if got, want := string(msg.Data), "marshaled"; got != want {
+			t.Errorf("Publish message has data %v, want %v", got, want)
+		}
+		if _, ok := msg.Metadata["content-type"]; ok {
+			t.Errorf("Publish message should not have content type")
+		}
 	}
+}
+
+func TestPublisher_OnFailPublish(t *testing.T) {
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		return errors.New("unfortunate error")
+	})
 	var calledCnt int
 	logger := new(fakeLogger)
 	publisher := pubee.New(driver,
@@ -195,8 +310,427 @@ func TestPublisher_OnFailPublish(t *testing.T) {
 	}
 }
 
+func TestPublisher_PublishResult(t *testing.T) {
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if string(msg.Data) == "fail" {
+			return errors.New("unfortunate error")
+		}
+		return nil
+	})
+	publisher := pubee.New(driver)
+	defer publisher.Close(context.Background())
+
+	id, err := publisher.Publish(context.Background(), "foobarbaz").Get(context.Background())
+	if err != nil {
+		t.Errorf("PublishResult.Get() returned %v, want nil", err)
+	}
+	if got, want := id, "1"; got != want {
+		t.Errorf("PublishResult.Get() returned id %q, want %q", got, want)
+	}
+
+	_, err = publisher.Publish(context.Background(), "fail").Get(context.Background())
+	if got, want := err, "unfortunate error"; got == nil || got.Error() != want {
+		t.Errorf("PublishResult.Get() returned %v, want %q", got, want)
+	}
+}
+
+func TestPublisher_WithRetry(t *testing.T) {
+	cases := []struct {
+		test         string
+		err          error
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			test:         "succeeded after retries",
+			err:          pubee.Retryable(errors.New("temporary error")),
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			test:         "exceeded max attempts",
+			err:          pubee.Retryable(errors.New("temporary error")),
+			failures:     5,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			test:         "permanent error",
+			err:          errors.New("permanent error"),
+			failures:     5,
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			var attempts int
+			driver := memory.NewDriver()
+			driver.InjectFailure(func(msg *pubee.Message) error {
+				attempts++
+				if attempts <= tc.failures {
+					return tc.err
+				}
+				return nil
+			})
+			publisher := pubee.New(driver,
+				pubee.WithRetry(pubee.RetryConfig{
+					MaxAttempts:    3,
+					InitialBackoff: time.Millisecond,
+				}),
+			)
+			defer publisher.Close(context.Background())
+
+			_, err := publisher.Publish(context.Background(), "foobarbaz").Get(context.Background())
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("PublishResult.Get() returned %v, want error: %t", err, want)
+			}
+			if got, want := attempts, tc.wantAttempts; got != want {
+				t.Errorf("Publish is attempted %d times, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPublisher_WithRetry_Interceptors(t *testing.T) {
+	var attempts int
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		attempts++
+		if attempts == 1 {
+			return pubee.Retryable(errors.New("temporary error"))
+		}
+		return nil
+	})
+
+	var (
+		calls    int
+		observed []error
+		mu       sync.Mutex
+	)
+	publisher := pubee.New(driver,
+		pubee.WithRetry(pubee.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		}),
+		pubee.WithPublishInterceptors(func(ctx context.Context, msg *pubee.Message, h pubee.PublishHandler) <-chan error {
+			calls++
+			msg.Data = append(msg.Data, '!')
+			errCh := make(chan error, 1)
+			go func() {
+				defer close(errCh)
+				err := <-h(ctx, msg)
+				mu.Lock()
+				observed = append(observed, err)
+				mu.Unlock()
+				errCh <- err
+			}()
+			return errCh
+		}),
+	)
+
+	_, err := publisher.Publish(context.Background(), "foobarbaz").Get(context.Background())
+	publisher.Close(context.Background())
+
+	if err != nil {
+		t.Errorf("PublishResult.Get() returned %v, want nil", err)
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("Interceptor is called %d times, want %d", got, want)
+	}
+	if got, want := len(observed), 1; got != want || observed[0] != nil {
+		t.Errorf("Interceptor observed %v, want a nil error", observed)
+	}
+	if got, want := string(driver.Messages()[0].Data), "foobarbaz!"; got != want {
+		t.Errorf("Published message has data %q, want %q", got, want)
+	}
+}
+
+func TestPublisher_WithRetry_Deadline(t *testing.T) {
+	var attempts int
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		attempts++
+		return pubee.Retryable(errors.New("temporary error"))
+	})
+	publisher := pubee.New(driver,
+		pubee.WithRetry(pubee.RetryConfig{
+			MaxAttempts:    100,
+			InitialBackoff: 10 * time.Millisecond,
+			Multiplier:     1,
+			Deadline:       35 * time.Millisecond,
+		}),
+	)
+	defer publisher.Close(context.Background())
+
+	_, err := publisher.Publish(context.Background(), "foobarbaz").Get(context.Background())
+	if err == nil {
+		t.Error("PublishResult.Get() should return an error")
+	}
+	if attempts < 2 || attempts > 5 {
+		t.Errorf("Publish is attempted %d times, want between 2 and 5", attempts)
+	}
+}
+
+func TestPublisher_WithRetry_DeadlineWhenHanging(t *testing.T) {
+	driver := new(pendingDriver)
+	publisher := pubee.New(driver,
+		pubee.WithRetry(pubee.RetryConfig{
+			Deadline: 20 * time.Millisecond,
+		}),
+	)
+	defer publisher.Close(context.Background())
+	defer driver.ReleaseAll()
+
+	res := publisher.Publish(context.Background(), "foobarbaz")
+
+	select {
+	case <-res.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("Publish should give up at the deadline")
+	}
+	if _, err := res.Get(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PublishResult.Get() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := driver.Context(0).Err(); err != context.DeadlineExceeded {
+		t.Errorf("The context of the attempt has error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPublisher_WithDeadLetterSink(t *testing.T) {
+	var deadLetters []*pubee.DeadLetter
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if string(msg.Data) == "fail" {
+			return pubee.Retryable(errors.New("temporary error"))
+		}
+		return nil
+	})
+	publisher := pubee.New(driver,
+		pubee.WithRetry(pubee.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		}),
+		pubee.WithDeadLetterSink(pubee.DeadLetterSinkFunc(func(ctx context.Context, dl *pubee.DeadLetter) error {
+			deadLetters = append(deadLetters, dl)
+			return nil
+		})),
+	)
+
+	publisher.Publish(context.Background(), "ok")
+	publisher.Publish(context.Background(), "fail", pubee.WithTopic("awesometopic"))
+	publisher.Publish(context.Background(), "foobarbaz", pubee.WithProtobuf())
+	publisher.Close(context.Background())
+
+	if got, want := len(deadLetters), 1; got != want {
+		t.Fatalf("Dead letters are %d, want %d", got, want)
+	}
+	dl := deadLetters[0]
+	if got, want := string(dl.Message.Data), "fail"; got != want {
+		t.Errorf("Dead letter has data %q, want %q", got, want)
+	}
+	if got, want := dl.Message.Topic, "awesometopic"; got != want {
+		t.Errorf("Dead letter has topic %q, want %q", got, want)
+	}
+	if got, want := dl.Attempts, 3; got != want {
+		t.Errorf("Dead letter has %d attempts, want %d", got, want)
+	}
+	if dl.Err == nil {
+		t.Error("Dead letter should have an error")
+	}
+	if dl.FailedAt.IsZero() {
+		t.Error("Dead letter should have a failed time")
+	}
+}
+
+func TestPublisher_Close(t *testing.T) {
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver)
+
+	publisher.Publish(context.Background(), "foo")
+
+	if err := publisher.Close(context.Background()); err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+	if got, want := len(driver.Messages()), 1; got != want {
+		t.Errorf("Published messages are %d, want %d", got, want)
+	}
+
+	_, err := publisher.Publish(context.Background(), "bar").Get(context.Background())
+	if got, want := err, pubee.ErrClosed; got != want {
+		t.Errorf("PublishResult.Get() after Close() returned %v, want %v", got, want)
+	}
+	if got, want := publisher.Close(context.Background()), pubee.ErrClosed; got != want {
+		t.Errorf("Close() after Close() returned %v, want %v", got, want)
+	}
+}
+
+func TestPublisher_Close_Drain(t *testing.T) {
+	driver := new(pendingDriver)
+	publisher := pubee.New(driver)
+
+	res := publisher.Publish(context.Background(), "foo")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		driver.ReleaseAll()
+	}()
+
+	if err := publisher.Close(context.Background()); err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+	if _, err := res.Get(context.Background()); err != nil {
+		t.Errorf("PublishResult.Get() returned %v, want nil", err)
+	}
+}
+
+// stoppingDriver fails messages published after Flush, like Cloud Pub/Sub topics stopped by Flush.
+type stoppingDriver struct {
+	entered chan struct{}
+	proceed chan struct{}
+
+	mu      sync.Mutex
+	flushed bool
+}
+
+func (d *stoppingDriver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	close(d.entered)
+	<-d.proceed
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	if d.flushed {
+		errCh <- errors.New("stopped")
+	}
+	close(errCh)
+	return errCh
+}
+
+func (d *stoppingDriver) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flushed = true
+}
+
+func (d *stoppingDriver) Close(ctx context.Context) error { return nil }
+
+func TestPublisher_Close_DuringPublish(t *testing.T) {
+	driver := &stoppingDriver{entered: make(chan struct{}), proceed: make(chan struct{})}
+	publisher := pubee.New(driver)
+
+	resCh := make(chan *pubee.PublishResult)
+	go func() { resCh <- publisher.Publish(context.Background(), "foo") }()
+	<-driver.entered
+
+	closed := make(chan error)
+	go func() { closed <- publisher.Close(context.Background()) }()
+
+	select {
+	case <-closed:
+		t.Fatal("Close() should wait for Publish() handing the message to the driver")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(driver.proceed)
+
+	if _, err := (<-resCh).Get(context.Background()); err != nil {
+		t.Errorf("PublishResult.Get() returned %v, want nil", err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+}
+
+// finalFlushDriver fails messages published after Flush, like Cloud Pub/Sub topics stopped by Flush.
+type finalFlushDriver struct {
+	*memory.Driver
+
+	mu      sync.Mutex
+	flushed bool
+}
+
+func (d *finalFlushDriver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.flushed {
+		errCh := make(chan error, 1)
+		errCh <- errors.New("stopped")
+		close(errCh)
+		return errCh
+	}
+	return d.Driver.Publish(ctx, msg)
+}
+
+func (d *finalFlushDriver) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flushed = true
+}
+
+func TestPublisher_Close_DuringRetry(t *testing.T) {
+	var attempts int32
+	driver := &finalFlushDriver{Driver: memory.NewDriver()}
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return pubee.Retryable(errors.New("temporary error"))
+		}
+		return nil
+	})
+	publisher := pubee.New(driver,
+		pubee.WithRetry(pubee.RetryConfig{
+			InitialBackoff: 20 * time.Millisecond,
+		}),
+		pubee.WithDeadLetterSink(pubee.DeadLetterSinkFunc(func(ctx context.Context, dl *pubee.DeadLetter) error {
+			t.Errorf("Message %q should not be a dead letter: %v", dl.Message.Data, dl.Err)
+			return nil
+		})),
+	)
+
+	res := publisher.Publish(context.Background(), "foo")
+	if err := publisher.Close(context.Background()); err != nil {
+		t.Errorf("Close() returned %v, want nil", err)
+	}
+
+	if _, err := res.Get(context.Background()); err != nil {
+		t.Errorf("PublishResult.Get() returned %v, want nil", err)
+	}
+	if got, want := atomic.LoadInt32(&attempts), int32(2); got != want {
+		t.Errorf("Publish is attempted %d times, want %d", got, want)
+	}
+}
+
+func TestPublisher_Close_Abandoned(t *testing.T) {
+	driver := new(pendingDriver)
+	defer driver.ReleaseAll()
+	publisher := pubee.New(driver)
+
+	publisher.Publish(context.Background(), "foo")
+	publisher.Publish(context.Background(), "bar")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := publisher.Close(ctx)
+
+	var abandonedErr *pubee.AbandonedError
+	if !errors.As(err, &abandonedErr) {
+		t.Fatalf("Close() returned %v, want an AbandonedError", err)
+	}
+	if got, want := abandonedErr.Count, 2; got != want {
+		t.Errorf("Abandoned messages are %d, want %d", got, want)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
 func TestPublisher_WhenFailMarshal(t *testing.T) {
-	driver := new(fakeDriver)
+	driver := memory.NewDriver()
 	var calledCnt int
 	publisher := pubee.New(driver,
 		pubee.WithProtobuf(),
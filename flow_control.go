@@ -0,0 +1,134 @@
+package pubee
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrFlowControlLimitExceeded is returned when publishing a message over the in-flight limits with FlowControlFailFast.
+	ErrFlowControlLimitExceeded = errors.New("pubee: flow control limit exceeded")
+	// ErrMessageDropped is returned when an in-flight message is dropped to publish a newer one with FlowControlDropOldest.
+	ErrMessageDropped = errors.New("pubee: message dropped by flow control")
+)
+
+// LimitExceededBehavior is the behavior when publishing a message over the in-flight limits.
+type LimitExceededBehavior int
+
+const (
+	// FlowControlBlock blocks publishing until in-flight messages complete or the context is done.
+	FlowControlBlock LimitExceededBehavior = iota
+	// FlowControlFailFast fails publishing with ErrFlowControlLimitExceeded.
+	FlowControlFailFast
+	// FlowControlDropOldest fails the oldest in-flight messages with ErrMessageDropped to make room.
+	// The contexts passed to drivers for dropped messages are canceled, but drivers ignoring them may still deliver the messages,
+	// so dropped messages are reported to OnFailPublish but not to the DeadLetterSink.
+	FlowControlDropOldest
+)
+
+// FlowControlConfig represents limits of in-flight messages, which are published but whose results are not ready.
+type FlowControlConfig struct {
+	// MaxMessages is the maximum number of in-flight messages. No limit when zero.
+	MaxMessages int
+	// MaxBytes is the maximum total size of in-flight message data. No limit when zero.
+	// A message larger than MaxBytes is published only when no other messages are in flight.
+	MaxBytes int
+	// LimitExceededBehavior is the behavior when the limits are exceeded. Defaults to FlowControlBlock.
+	LimitExceededBehavior LimitExceededBehavior
+}
+
+type flowController struct {
+	cfg FlowControlConfig
+
+	mu       sync.Mutex
+	messages int
+	bytes    int
+	entries  list.List
+	changed  chan struct{}
+}
+
+type flowEntry struct {
+	size    int
+	elem    *list.Element
+	dropped chan struct{}
+}
+
+func newFlowController(cfg FlowControlConfig) *flowController {
+	return &flowController{cfg: cfg, changed: make(chan struct{})}
+}
+
+// acquire reserves room for a message of the size.
+func (fc *flowController) acquire(ctx context.Context, size int) (*flowEntry, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	for !fc.fits(size) {
+		switch fc.cfg.LimitExceededBehavior {
+		case FlowControlFailFast:
+			return nil, ErrFlowControlLimitExceeded
+		case FlowControlDropOldest:
+			oldest := fc.entries.Front().Value.(*flowEntry)
+			fc.remove(oldest)
+			close(oldest.dropped)
+			continue
+		}
+
+		changed := fc.changed
+		fc.mu.Unlock()
+		select {
+		case <-changed:
+			fc.mu.Lock()
+		case <-ctx.Done():
+			fc.mu.Lock()
+			return nil, ctx.Err()
+		}
+	}
+
+	e := &flowEntry{size: size, dropped: make(chan struct{})}
+	e.elem = fc.entries.PushBack(e)
+	fc.messages++
+	fc.bytes += size
+
+	return e, nil
+}
+
+// release frees room for the message. It does nothing when the message has been dropped.
+func (fc *flowController) release(e *flowEntry) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if e.elem != nil {
+		fc.remove(e)
+	}
+}
+
+func (fc *flowController) remove(e *flowEntry) {
+	fc.entries.Remove(e.elem)
+	e.elem = nil
+	fc.messages--
+	fc.bytes -= e.size
+
+	close(fc.changed)
+	fc.changed = make(chan struct{})
+}
+
+func (fc *flowController) fits(size int) bool {
+	if fc.messages == 0 {
+		return true
+	}
+	if max := fc.cfg.MaxMessages; max > 0 && fc.messages+1 > max {
+		return false
+	}
+	if max := fc.cfg.MaxBytes; max > 0 && fc.bytes+size > max {
+		return false
+	}
+	return true
+}
+
+func (fc *flowController) inFlight() (messages, bytes int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.messages, fc.bytes
+}
@@ -0,0 +1,175 @@
+package pubee_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee"
+)
+
+// pendingDriver holds published messages until they are released.
+type pendingDriver struct {
+	mu      sync.Mutex
+	pending []chan error
+	ctxs    []context.Context
+}
+
+func (d *pendingDriver) Publish(ctx context.Context, msg *pubee.Message) <-chan error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	errCh := make(chan error, 1)
+	d.pending = append(d.pending, errCh)
+	d.ctxs = append(d.ctxs, ctx)
+	return errCh
+}
+
+// Context returns the context the i-th message was published with.
+func (d *pendingDriver) Context(i int) context.Context {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ctxs[i]
+}
+
+// Release completes the oldest pending message.
+func (d *pendingDriver) Release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	close(d.pending[0])
+	d.pending = d.pending[1:]
+}
+
+func (d *pendingDriver) ReleaseAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, errCh := range d.pending {
+		close(errCh)
+	}
+	d.pending = nil
+}
+
+func (d *pendingDriver) Flush()                          {}
+func (d *pendingDriver) Close(ctx context.Context) error { return nil }
+
+func TestPublisher_WithFlowControl_Block(t *testing.T) {
+	driver := new(pendingDriver)
+	publisher := pubee.New(driver, pubee.WithFlowControl(pubee.FlowControlConfig{MaxMessages: 2}))
+	defer publisher.Close(context.Background())
+	defer driver.ReleaseAll()
+
+	publisher.Publish(context.Background(), "foo")
+	publisher.Publish(context.Background(), "bar")
+
+	if got, want := inFlightMessages(publisher), 2; got != want {
+		t.Errorf("InFlight() returned %d messages, want %d", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := publisher.Publish(ctx, "baz").Get(context.Background())
+	if got, want := err, context.DeadlineExceeded; got != want {
+		t.Errorf("PublishResult.Get() returned %v, want %v", got, want)
+	}
+
+	resCh := make(chan *pubee.PublishResult)
+	go func() {
+		resCh <- publisher.Publish(context.Background(), "baz")
+	}()
+
+	select {
+	case <-resCh:
+		t.Fatal("Publish() should block while the limit is exceeded")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	driver.Release()
+
+	select {
+	case <-resCh:
+	case <-time.After(time.Second):
+		t.Fatal("Publish() should be unblocked after an in-flight message completes")
+	}
+}
+
+func TestPublisher_WithFlowControl_FailFast(t *testing.T) {
+	driver := new(pendingDriver)
+	publisher := pubee.New(driver, pubee.WithFlowControl(pubee.FlowControlConfig{
+		MaxBytes:              6,
+		LimitExceededBehavior: pubee.FlowControlFailFast,
+	}))
+	defer publisher.Close(context.Background())
+	defer driver.ReleaseAll()
+
+	publisher.Publish(context.Background(), "foo")
+	publisher.Publish(context.Background(), "bar")
+
+	_, err := publisher.Publish(context.Background(), "baz").Get(context.Background())
+	if got, want := err, pubee.ErrFlowControlLimitExceeded; got != want {
+		t.Errorf("PublishResult.Get() returned %v, want %v", got, want)
+	}
+
+	if got, want := inFlightBytes(publisher), 6; got != want {
+		t.Errorf("InFlight() returned %d bytes, want %d", got, want)
+	}
+}
+
+func TestPublisher_WithFlowControl_DropOldest(t *testing.T) {
+	driver := new(pendingDriver)
+	var dropped []string
+	publisher := pubee.New(driver,
+		pubee.WithFlowControl(pubee.FlowControlConfig{
+			MaxMessages:           2,
+			LimitExceededBehavior: pubee.FlowControlDropOldest,
+		}),
+		pubee.WithOnFailPublish(func(msg *pubee.Message, err error) {
+			dropped = append(dropped, string(msg.Data))
+		}),
+		pubee.WithDeadLetterSink(pubee.DeadLetterSinkFunc(func(ctx context.Context, dl *pubee.DeadLetter) error {
+			t.Errorf("Dropped message %q should not be a dead letter", dl.Message.Data)
+			return nil
+		})),
+	)
+	defer publisher.Close(context.Background())
+	defer driver.ReleaseAll()
+
+	res1 := publisher.Publish(context.Background(), "foo")
+	res2 := publisher.Publish(context.Background(), "bar")
+	publisher.Publish(context.Background(), "baz")
+
+	_, err := res1.Get(context.Background())
+	if got, want := err, pubee.ErrMessageDropped; got != want {
+		t.Errorf("PublishResult.Get() returned %v, want %v", got, want)
+	}
+	select {
+	case <-driver.Context(0).Done():
+	case <-time.After(time.Second):
+		t.Error("The context of the dropped message should be canceled")
+	}
+	select {
+	case <-res2.Ready():
+		t.Error("The second message should not be dropped")
+	case <-driver.Context(1).Done():
+		t.Error("The context of the second message should not be canceled")
+	default:
+	}
+	if got, want := inFlightMessages(publisher), 2; got != want {
+		t.Errorf("InFlight() returned %d messages, want %d", got, want)
+	}
+	if got, want := len(dropped), 1; got != want {
+		t.Fatalf("Dropped messages are %d, want %d", got, want)
+	}
+	if got, want := dropped[0], "foo"; got != want {
+		t.Errorf("Dropped message has data %q, want %q", got, want)
+	}
+}
+
+func inFlightMessages(e pubee.Engine) int {
+	n, _ := e.InFlight()
+	return n
+}
+
+func inFlightBytes(e pubee.Engine) int {
+	_, n := e.InFlight()
+	return n
+}
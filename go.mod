@@ -1,11 +1,13 @@
 module github.com/izumin5210/pubee
 
-go 1.12
+go 1.13
 
 require (
-	cloud.google.com/go v0.39.0
-	github.com/golang/protobuf v1.3.1
-	github.com/google/go-cmp v0.3.0
-	google.golang.org/api v0.5.0
-	google.golang.org/grpc v1.19.0
+	cloud.google.com/go/pubsub v1.5.0
+	github.com/golang/protobuf v1.4.2
+	github.com/google/go-cmp v0.5.0
+	google.golang.org/api v0.28.0
+	google.golang.org/genproto v0.0.0-20200707001353-8e8330bf89df
+	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.25.0
 )
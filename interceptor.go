@@ -0,0 +1,65 @@
+package pubee
+
+import "context"
+
+// Interceptor intercepts publishing a message.
+// It cannot observe the result of publishing. Use PublishInterceptor to do so.
+type Interceptor func(context.Context, *Message, func(context.Context, *Message))
+
+// PublishHandler publishes a message and returns a channel that receives the result.
+type PublishHandler func(context.Context, *Message) <-chan error
+
+// PublishInterceptor intercepts publishing a message.
+// The channel returned from the handler receives the result of publishing by the driver.
+type PublishInterceptor func(context.Context, *Message, PublishHandler) <-chan error
+
+func (f Interceptor) publishInterceptor() PublishInterceptor {
+	return func(ctx context.Context, msg *Message, handler PublishHandler) <-chan error {
+		var errCh <-chan error
+		f(ctx, msg, func(ctx context.Context, msg *Message) {
+			errCh = handler(ctx, msg)
+		})
+		return errCh
+	}
+}
+
+func chainPublishInterceptors(interceptors []PublishInterceptor) PublishInterceptor {
+	if len(interceptors) == 1 {
+		return interceptors[0]
+	}
+
+	return func(ctx context.Context, msg *Message, handler PublishHandler) <-chan error {
+		return interceptors[0](ctx, msg, chainPublishHandler(interceptors, 0, handler))
+	}
+}
+
+func chainPublishHandler(interceptors []PublishInterceptor, cur int, final PublishHandler) PublishHandler {
+	if cur == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, msg *Message) <-chan error {
+		return interceptors[cur+1](ctx, msg, chainPublishHandler(interceptors, cur+1, final))
+	}
+}
+
+// SubscribeInterceptor intercepts handling a received message.
+type SubscribeInterceptor func(context.Context, *ReceivedMessage, Handler) error
+
+func chainSubscribeInterceptors(interceptors []SubscribeInterceptor) SubscribeInterceptor {
+	if len(interceptors) == 1 {
+		return interceptors[0]
+	}
+
+	return func(ctx context.Context, msg *ReceivedMessage, handler Handler) error {
+		return interceptors[0](ctx, msg, chainHandler(interceptors, 0, handler))
+	}
+}
+
+func chainHandler(interceptors []SubscribeInterceptor, cur int, final Handler) Handler {
+	if cur == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, msg *ReceivedMessage) error {
+		return interceptors[cur+1](ctx, msg, chainHandler(interceptors, cur+1, final))
+	}
+}
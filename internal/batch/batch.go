@@ -0,0 +1,181 @@
+// Package batch buffers entries of drivers and sends them in batches in added order.
+package batch
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxEntries is the maximum number of entries in a batch when Config.MaxEntries is not positive.
+	DefaultMaxEntries = 100
+	// DefaultTimeout is how long entries are buffered when Config.Timeout is not positive.
+	DefaultTimeout = 10 * time.Millisecond
+)
+
+// Config represents batching configuration.
+type Config struct {
+	// MaxEntries is the maximum number of entries in a batch. Defaults to DefaultMaxEntries.
+	MaxEntries int
+	// MaxBytes is the maximum total size of entries in a batch. No limit when zero.
+	// An entry larger than MaxBytes is sent alone.
+	MaxBytes int
+	// Timeout is how long entries are buffered before sent. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Entry is a buffered entry.
+type Entry struct {
+	// Key is the destination of the entry. Entries with different keys are sent in different batches.
+	Key string
+	// Size is the size of the entry counted for Config.MaxBytes.
+	Size int
+	// Value is the driver specific payload.
+	Value interface{}
+}
+
+// SendFunc sends entries with the same key, and reports their results.
+type SendFunc func(key string, entries []*Entry)
+
+// Batcher buffers entries and sends them in added order.
+type Batcher struct {
+	send SendFunc
+	cfg  Config
+
+	mu       sync.Mutex
+	buf      []*Entry
+	bufBytes int
+	closed   bool
+	notifyCh chan struct{}
+	flushCh  chan chan struct{}
+	doneCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a Batcher that sends entries with send in a background goroutine.
+func New(send SendFunc, cfg Config) *Batcher {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	if cfg.MaxBytes < 0 {
+		cfg.MaxBytes = 0
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	b := &Batcher{
+		send:     send,
+		cfg:      cfg,
+		notifyCh: make(chan struct{}, 1),
+		flushCh:  make(chan chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	return b
+}
+
+// Add buffers the entry. It returns false if the Batcher is closed.
+func (b *Batcher) Add(e *Entry) bool {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return false
+	}
+	b.buf = append(b.buf, e)
+	b.bufBytes += e.Size
+	full := len(b.buf) >= b.cfg.MaxEntries || (b.cfg.MaxBytes > 0 && b.bufBytes >= b.cfg.MaxBytes)
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.notifyCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
+
+// Flush sends all buffered entries and waits for them to be sent.
+func (b *Batcher) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case b.flushCh <- flushed:
+		<-flushed
+	case <-b.doneCh:
+	}
+}
+
+// Close sends all buffered entries and stops the Batcher. It returns false if the Batcher is already closed.
+func (b *Batcher) Close() bool {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return false
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	b.Flush()
+	close(b.doneCh)
+	b.wg.Wait()
+
+	return true
+}
+
+func (b *Batcher) run() {
+	defer b.wg.Done()
+
+	t := time.NewTicker(b.cfg.Timeout)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-b.notifyCh:
+			b.sendAll()
+		case <-t.C:
+			b.sendAll()
+		case flushed := <-b.flushCh:
+			b.sendAll()
+			close(flushed)
+		case <-b.doneCh:
+			b.sendAll()
+			return
+		}
+	}
+}
+
+func (b *Batcher) sendAll() {
+	for {
+		batch := b.next()
+		if len(batch) == 0 {
+			return
+		}
+		b.send(batch[0].Key, batch)
+	}
+}
+
+// next takes leading entries that can be sent in a single batch.
+func (b *Batcher) next() []*Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n, size := 0, 0
+	for _, e := range b.buf {
+		if n == b.cfg.MaxEntries || e.Key != b.buf[0].Key || (n > 0 && b.cfg.MaxBytes > 0 && size+e.Size > b.cfg.MaxBytes) {
+			break
+		}
+		n++
+		size += e.Size
+	}
+
+	batch := b.buf[:n]
+	b.buf = b.buf[n:]
+	b.bufBytes -= size
+
+	return batch
+}
@@ -0,0 +1,128 @@
+package batch_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/izumin5210/pubee/internal/batch"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches []string
+}
+
+func (r *recorder) send(key string, entries []*batch.Entry) {
+	values := make([]string, len(entries))
+	for i, e := range entries {
+		values[i] = e.Value.(string)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, key+":"+strings.Join(values, ","))
+}
+
+func (r *recorder) Batches() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.batches, " ")
+}
+
+func TestBatcher(t *testing.T) {
+	cases := []struct {
+		test    string
+		cfg     batch.Config
+		entries []*batch.Entry
+		want    string
+	}{
+		{
+			test:    "max entries",
+			cfg:     batch.Config{MaxEntries: 2, Timeout: time.Hour},
+			entries: []*batch.Entry{{Value: "0"}, {Value: "1"}, {Value: "2"}},
+			want:    ":0,1 :2",
+		},
+		{
+			test:    "keys",
+			cfg:     batch.Config{Timeout: time.Hour},
+			entries: []*batch.Entry{{Key: "a", Value: "0"}, {Key: "b", Value: "1"}, {Key: "b", Value: "2"}, {Key: "a", Value: "3"}},
+			want:    "a:0 b:1,2 a:3",
+		},
+		{
+			test:    "max bytes",
+			cfg:     batch.Config{MaxBytes: 10, Timeout: time.Hour},
+			entries: []*batch.Entry{{Size: 4, Value: "0"}, {Size: 4, Value: "1"}, {Size: 4, Value: "2"}, {Size: 20, Value: "3"}},
+			want:    ":0,1 :2 :3",
+		},
+		{
+			test:    "non-positive values",
+			cfg:     batch.Config{MaxEntries: -1, MaxBytes: -1, Timeout: -1},
+			entries: []*batch.Entry{{Size: 4, Value: "0"}, {Size: 4, Value: "1"}},
+			want:    ":0,1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.test, func(t *testing.T) {
+			r := new(recorder)
+			b := batch.New(r.send, tc.cfg)
+
+			for _, e := range tc.entries {
+				if !b.Add(e) {
+					t.Errorf("Add() returned false")
+				}
+			}
+			b.Flush()
+
+			if got, want := r.Batches(), tc.want; got != want {
+				t.Errorf("Sent batches are %q, want %q", got, want)
+			}
+
+			if got, want := b.Close(), true; got != want {
+				t.Errorf("Close() returned %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestBatcher_Timeout(t *testing.T) {
+	r := new(recorder)
+	b := batch.New(r.send, batch.Config{Timeout: time.Millisecond})
+	defer b.Close()
+
+	b.Add(&batch.Entry{Value: "0"})
+
+	deadline := time.Now().Add(time.Second)
+	for r.Batches() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("Buffered entries should be sent after the timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatcher_Close(t *testing.T) {
+	r := new(recorder)
+	b := batch.New(r.send, batch.Config{Timeout: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		b.Add(&batch.Entry{Value: fmt.Sprint(i)})
+	}
+
+	if got, want := b.Close(), true; got != want {
+		t.Errorf("Close() returned %t, want %t", got, want)
+	}
+	if got, want := r.Batches(), ":0,1,2"; got != want {
+		t.Errorf("Sent batches are %q, want %q", got, want)
+	}
+	if got, want := b.Add(&batch.Entry{Value: "3"}), false; got != want {
+		t.Errorf("Add() returned %t after Close(), want %t", got, want)
+	}
+	if got, want := b.Close(), false; got != want {
+		t.Errorf("Close() returned %t, want %t", got, want)
+	}
+	b.Flush()
+}
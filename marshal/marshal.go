@@ -1,3 +1,6 @@
+// Package marshal provides functions to marshal message payloads.
+//
+// Deprecated: Use the codec package, which also reports content types of payloads.
 package marshal
 
 import (
@@ -0,0 +1,160 @@
+// Package metrics provides Prometheus metrics for pubee.
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/izumin5210/pubee"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+// Metrics is a prometheus.Collector that collects metrics of publishing messages.
+type Metrics struct {
+	cfg       *Config
+	published *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	size      *prometheus.HistogramVec
+	inflight  *prometheus.GaugeVec
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+func New(opts ...Option) *Metrics {
+	cfg := newConfig(opts)
+
+	return &Metrics{
+		cfg: cfg,
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Name:      "published_messages_total",
+			Help:      "Total number of published messages.",
+		}, []string{"topic", "driver", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Name:      "publish_duration_seconds",
+			Help:      "Time taken for the driver to publish a message.",
+			Buckets:   cfg.DurationBuckets,
+		}, []string{"topic", "driver", "outcome"}),
+		size: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Name:      "message_size_bytes",
+			Help:      "Size of published message payloads.",
+			Buckets:   cfg.SizeBuckets,
+		}, []string{"topic", "driver"}),
+		inflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.Namespace,
+			Name:      "inflight_messages",
+			Help:      "Number of messages waiting for the publish result.",
+		}, []string{"topic", "driver"}),
+	}
+}
+
+// WithMetrics returns a pubee.Option that collects metrics and registers them to reg.
+// When metrics with the same names are already registered, they are shared with the labels of opts.
+// Other registration errors are reported to the error log of the engine.
+func WithMetrics(reg prometheus.Registerer, opts ...Option) pubee.Option {
+	m, err := Register(reg, opts...)
+	if err != nil {
+		var once sync.Once
+		return pubee.WithPublishInterceptors(
+			func(ctx context.Context, msg *pubee.Message, handler pubee.PublishHandler) <-chan error {
+				once.Do(func() { pubee.GetErrorLog(ctx).Printf("failed to register metrics: %v", err) })
+				return handler(ctx, msg)
+			},
+			m.PublishInterceptor(),
+		)
+	}
+	return m.Option()
+}
+
+// Register creates Metrics and registers them to reg.
+// When metrics with the same names are already registered, the returned Metrics share their collectors with the labels of opts.
+func Register(reg prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	m := New(opts...)
+	if err := reg.Register(m); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return m, err
+		}
+		existing, ok := are.ExistingCollector.(*Metrics)
+		if !ok {
+			return m, err
+		}
+		return &Metrics{
+			cfg:       m.cfg,
+			published: existing.published,
+			duration:  existing.duration,
+			size:      existing.size,
+			inflight:  existing.inflight,
+		}, nil
+	}
+	return m, nil
+}
+
+// Option returns a pubee.Option that collects metrics.
+func (m *Metrics) Option() pubee.Option {
+	return pubee.WithPublishInterceptors(m.PublishInterceptor())
+}
+
+// PublishInterceptor returns a pubee.PublishInterceptor that collects metrics.
+func (m *Metrics) PublishInterceptor() pubee.PublishInterceptor {
+	return func(ctx context.Context, msg *pubee.Message, handler pubee.PublishHandler) <-chan error {
+		topic, driver := msg.Topic, m.cfg.Driver
+		if topic == "" {
+			topic = m.cfg.Topic
+		}
+
+		m.size.WithLabelValues(topic, driver).Observe(float64(len(msg.Data)))
+
+		inflight := m.inflight.WithLabelValues(topic, driver)
+		inflight.Inc()
+		start := time.Now()
+
+		errCh := handler(ctx, msg)
+		if errCh == nil {
+			inflight.Dec()
+			return nil
+		}
+
+		outCh := make(chan error, 1)
+		go func() {
+			defer close(outCh)
+			defer inflight.Dec()
+
+			err := <-errCh
+			outcome := outcomeSuccess
+			if err != nil {
+				outcome = outcomeFailure
+			}
+			m.published.WithLabelValues(topic, driver, outcome).Inc()
+			m.duration.WithLabelValues(topic, driver, outcome).Observe(time.Since(start).Seconds())
+			if err != nil {
+				outCh <- err
+			}
+		}()
+
+		return outCh
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.published.Describe(ch)
+	m.duration.Describe(ch)
+	m.size.Describe(ch)
+	m.inflight.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.published.Collect(ch)
+	m.duration.Collect(ch)
+	m.size.Collect(ch)
+	m.inflight.Collect(ch)
+}
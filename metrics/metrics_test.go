@@ -0,0 +1,158 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+	"github.com/izumin5210/pubee/metrics"
+)
+
+type blockingDriver struct {
+	errCh chan error
+}
+
+func (d *blockingDriver) Publish(context.Context, *pubee.Message) <-chan error { return d.errCh }
+func (d *blockingDriver) Flush()                                               {}
+func (d *blockingDriver) Close(context.Context) error                          { return nil }
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(msg *pubee.Message) error {
+		if string(msg.Data) == "fail" {
+			return errors.New("unfortunate error")
+		}
+		return nil
+	})
+	publisher := pubee.New(driver,
+		metrics.WithMetrics(reg, metrics.WithDriverName("memory"), metrics.WithTopic("awesometopic")),
+	)
+
+	publisher.Publish(ctx, "foobarbaz").Get(ctx)
+	publisher.Publish(ctx, "foobarbaz").Get(ctx)
+	publisher.Publish(ctx, "fail").Get(ctx)
+	publisher.Close(ctx)
+
+	if got, want := testutil.CollectAndCount(reg, "pubee_publish_duration_seconds"), 2; got != want {
+		t.Errorf("publish_duration_seconds has %d series, want %d", got, want)
+	}
+
+	m := metrics.New(metrics.WithDriverName("memory"), metrics.WithTopic("awesometopic"))
+	if err := reg.Register(m); err == nil {
+		t.Error("Metrics should be already registered")
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "pubee_published_messages_total" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			var outcome string
+			for _, l := range metric.GetLabel() {
+				if l.GetName() == "outcome" {
+					outcome = l.GetValue()
+				}
+			}
+			want := map[string]float64{"success": 2, "failure": 1}[outcome]
+			if got := metric.GetCounter().GetValue(); got != want {
+				t.Errorf("published_messages_total{outcome=%q} is %v, want %v", outcome, got, want)
+			}
+		}
+	}
+}
+
+func TestMetrics_Inflight(t *testing.T) {
+	ctx := context.Background()
+
+	reg := prometheus.NewRegistry()
+	driver := &blockingDriver{errCh: make(chan error)}
+	publisher := pubee.New(driver, metrics.WithMetrics(reg))
+
+	res := publisher.Publish(ctx, "foobarbaz")
+
+	if got, want := gaugeValue(t, reg, "pubee_inflight_messages"), 1.0; got != want {
+		t.Errorf("inflight_messages is %v, want %v", got, want)
+	}
+
+	close(driver.errCh)
+	res.Get(ctx)
+	publisher.Close(ctx)
+
+	if got, want := gaugeValue(t, reg, "pubee_inflight_messages"), 0.0; got != want {
+		t.Errorf("inflight_messages is %v, want %v", got, want)
+	}
+}
+
+func TestWithMetrics_Shared(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+
+	for _, name := range []string{"memory", "kafka"} {
+		publisher := pubee.New(memory.NewDriver(), metrics.WithMetrics(reg, metrics.WithDriverName(name)))
+		publisher.Publish(ctx, "foobarbaz").Get(ctx)
+		publisher.Close(ctx)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		if mf.GetName() != "pubee_published_messages_total" {
+			continue
+		}
+		for _, metric := range mf.GetMetric() {
+			for _, l := range metric.GetLabel() {
+				if l.GetName() == "driver" {
+					got[l.GetValue()] = metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	if want := map[string]float64{"memory": 1, "kafka": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("published_messages_total by driver is %v, want %v", got, want)
+	}
+}
+
+func TestRegister_WhenConflicted(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "pubee",
+		Name:      "published_messages_total",
+		Help:      "Conflicting metric.",
+	}))
+
+	if _, err := metrics.Register(reg); err == nil {
+		t.Error("Register() should return an error")
+	}
+}
+
+func gaugeValue(t *testing.T, g prometheus.Gatherer, name string) float64 {
+	t.Helper()
+
+	mfs, err := g.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == name && len(mf.GetMetric()) == 1 {
+			return mf.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("gauge %s is not found", name)
+	return 0
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Config represents metrics configuration.
+type Config struct {
+	Namespace       string
+	Driver          string
+	Topic           string
+	DurationBuckets []float64
+	SizeBuckets     []float64
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is metrics Option
+type Option func(*Config)
+
+// WithNamespace returns an Option that set a namespace of metric names. Defaults to "pubee".
+func WithNamespace(ns string) Option {
+	return func(c *Config) {
+		c.Namespace = ns
+	}
+}
+
+// WithDriverName returns an Option that set a value of the "driver" label.
+func WithDriverName(name string) Option {
+	return func(c *Config) {
+		c.Driver = name
+	}
+}
+
+// WithTopic returns an Option that set a value of the "topic" label for messages without pubee.WithTopic.
+func WithTopic(topic string) Option {
+	return func(c *Config) {
+		c.Topic = topic
+	}
+}
+
+// WithDurationBuckets returns an Option that set buckets of the publish duration histogram.
+func WithDurationBuckets(buckets []float64) Option {
+	return func(c *Config) {
+		c.DurationBuckets = buckets
+	}
+}
+
+// WithSizeBuckets returns an Option that set buckets of the message size histogram.
+func WithSizeBuckets(buckets []float64) Option {
+	return func(c *Config) {
+		c.SizeBuckets = buckets
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		Namespace:       "pubee",
+		DurationBuckets: prometheus.DefBuckets,
+		SizeBuckets:     prometheus.ExponentialBuckets(64, 4, 10),
+	}
+	cfg.apply(opts)
+	return cfg
+}
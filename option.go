@@ -1,16 +1,20 @@
 package pubee
 
 import (
-	"context"
+	"errors"
 
+	"github.com/izumin5210/pubee/codec"
 	"github.com/izumin5210/pubee/marshal"
 )
 
 type Config struct {
 	PublishOpts       []PublishOption
 	ErrorLog          Logger
-	Interceptor       Interceptor
+	Interceptor       PublishInterceptor
 	OnFailPublishFunc func(*Message, error)
+	Retry             *RetryConfig
+	DeadLetterSink    DeadLetterSink
+	FlowControl       FlowControlConfig
 }
 
 func (c *Config) apply(opts []Option) {
@@ -28,8 +32,10 @@ type OptionFunc func(*Config)
 func (o OptionFunc) applyOption(c *Config) { o(c) }
 
 type PublishConfig struct {
-	Metadata map[string]string
-	Marshal  marshal.Func
+	Topic       string
+	OrderingKey string
+	Metadata    map[string]string
+	Codec       codec.Codec
 }
 
 func (c *PublishConfig) apply(opts []PublishOption) {
@@ -61,37 +67,42 @@ func WithErrorLog(l Logger) Option {
 	})
 }
 
+// WithInterceptors returns an Option that set Interceptor(s).
+// They can be chained with PublishInterceptor(s) registered by WithPublishInterceptors.
 func WithInterceptors(interceptors ...Interceptor) Option {
+	pis := make([]PublishInterceptor, len(interceptors))
+	for i, f := range interceptors {
+		pis[i] = f.publishInterceptor()
+	}
+	return WithPublishInterceptors(pis...)
+}
+
+// WithPublishInterceptors returns an Option that set PublishInterceptor(s) that can observe results of publishing.
+func WithPublishInterceptors(interceptors ...PublishInterceptor) Option {
 	return OptionFunc(func(c *Config) {
-		if f := c.Interceptor; f != nil {
-			interceptors = append([]Interceptor{f}, interceptors...)
-		}
-		n := len(interceptors)
-		if n == 1 {
-			c.Interceptor = interceptors[0]
+		if len(interceptors) == 0 {
 			return
 		}
-
-		// https://github.com/grpc-ecosystem/go-grpc-middleware/blob/v1.0.0/chain.go#L13-L51
-		lastI := n - 1
-		c.Interceptor = func(ctx context.Context, msg *Message, handler func(context.Context, *Message)) {
-			var (
-				chainHandler func(context.Context, *Message)
-				curI         int
-			)
-
-			chainHandler = func(currentCtx context.Context, currentMsg *Message) {
-				if curI == lastI {
-					handler(currentCtx, currentMsg)
-					return
-				}
-				curI++
-				interceptors[curI](currentCtx, currentMsg, chainHandler)
-				curI--
-			}
-
-			interceptors[0](ctx, msg, chainHandler)
+		chain := interceptors
+		if f := c.Interceptor; f != nil {
+			chain = append([]PublishInterceptor{f}, interceptors...)
 		}
+		c.Interceptor = chainPublishInterceptors(chain)
+	})
+}
+
+// WithTopic returns a PublishOption that set a topic messages are published to.
+func WithTopic(topic string) PublishOption {
+	return PublishOptionFunc(func(c *PublishConfig) {
+		c.Topic = topic
+	})
+}
+
+// WithOrderingKey returns a PublishOption that set an ordering key to messages.
+// Messages with the same ordering key are delivered in the order they are published, if the driver supports ordering.
+func WithOrderingKey(key string) PublishOption {
+	return PublishOptionFunc(func(c *PublishConfig) {
+		c.OrderingKey = key
 	})
 }
 
@@ -119,15 +130,34 @@ func WithMetadataMap(meta map[string]string) PublishOption {
 }
 
 func WithJSON() PublishOption {
-	return WithMarshalFunc(marshal.JSON)
+	return WithCodec(codec.JSON)
 }
 
 func WithProtobuf() PublishOption {
-	return WithMarshalFunc(marshal.Protobuf)
+	return WithCodec(codec.Protobuf)
+}
+
+// WithCodec returns a PublishOption that marshals messages with the codec.Codec.
+// The content type of the codec is stored into the message metadata.
+func WithCodec(c codec.Codec) PublishOption {
+	return PublishOptionFunc(func(cfg *PublishConfig) { cfg.Codec = c })
 }
 
+// WithMarshalFunc returns a PublishOption that marshals messages with the marshal.Func.
+//
+// Deprecated: Use WithCodec instead. Messages marshaled by marshal.Func have no content type.
 func WithMarshalFunc(f marshal.Func) PublishOption {
-	return PublishOptionFunc(func(c *PublishConfig) { c.Marshal = f })
+	return WithCodec(marshalFuncCodec(f))
+}
+
+type marshalFuncCodec marshal.Func
+
+func (marshalFuncCodec) ContentType() string { return "" }
+
+func (f marshalFuncCodec) Marshal(in interface{}) ([]byte, error) { return f(in) }
+
+func (marshalFuncCodec) Unmarshal([]byte, interface{}) error {
+	return errors.New("marshal.Func does not support unmarshaling")
 }
 
 func WithOnFailPublish(f func(*Message, error)) Option {
@@ -135,3 +165,27 @@ func WithOnFailPublish(f func(*Message, error)) Option {
 		c.OnFailPublishFunc = f
 	})
 }
+
+// WithRetry returns an Option that retries failed publishes with exponential backoff.
+func WithRetry(cfg RetryConfig) Option {
+	return OptionFunc(func(c *Config) {
+		cfg.setDefaults()
+		c.Retry = &cfg
+	})
+}
+
+// WithDeadLetterSink returns an Option that puts messages failed to be published, after all retries, into the sink.
+// Messages failed to be marshaled or rejected by flow control are not put, because they have not been published.
+func WithDeadLetterSink(s DeadLetterSink) Option {
+	return OptionFunc(func(c *Config) {
+		c.DeadLetterSink = s
+	})
+}
+
+// WithFlowControl returns an Option that limits in-flight messages.
+// Without limits, a broker outage lets in-flight messages and their goroutines grow without bound.
+func WithFlowControl(cfg FlowControlConfig) Option {
+	return OptionFunc(func(c *Config) {
+		c.FlowControl = cfg
+	})
+}
@@ -0,0 +1,45 @@
+package otel
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config represents tracing configuration.
+type Config struct {
+	TracerProvider trace.TracerProvider
+	Propagator     propagation.TextMapPropagator
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+func newConfig(opts []Option) *Config {
+	cfg := &Config{
+		TracerProvider: otel.GetTracerProvider(),
+		Propagator:     propagation.TraceContext{},
+	}
+	cfg.apply(opts)
+	return cfg
+}
+
+// Option is tracing Option
+type Option func(*Config)
+
+// WithTracerProvider returns an Option that set trace.TracerProvider. The global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Config) {
+		c.TracerProvider = tp
+	}
+}
+
+// WithPropagator returns an Option that set propagation.TextMapPropagator. W3C Trace Context is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *Config) {
+		c.Propagator = p
+	}
+}
@@ -0,0 +1,106 @@
+// Package otel provides OpenTelemetry tracing for pubee.
+// Trace context is propagated via message metadata using `traceparent` and `tracestate` keys.
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/izumin5210/pubee"
+)
+
+const tracerName = "github.com/izumin5210/pubee/otel"
+
+var (
+	attrSystem = attribute.String("messaging.system", "pubee")
+)
+
+// PublishInterceptor returns a pubee.PublishInterceptor that starts a producer span for each message.
+// The span ends when the driver returns the publish result.
+func PublishInterceptor(opts ...Option) pubee.PublishInterceptor {
+	cfg := newConfig(opts)
+	tracer := cfg.TracerProvider.Tracer(tracerName)
+
+	return func(ctx context.Context, msg *pubee.Message, handler pubee.PublishHandler) <-chan error {
+		ctx, span := tracer.Start(ctx, "publish",
+			trace.WithSpanKind(trace.SpanKindProducer),
+			trace.WithAttributes(
+				attrSystem,
+				attribute.String("messaging.operation", "publish"),
+				attribute.Int("messaging.message.body.size", len(msg.Data)),
+			),
+		)
+		if msg.Topic != "" {
+			span.SetAttributes(attribute.String("messaging.destination.name", msg.Topic))
+		}
+
+		// metadata map may be shared with other messages
+		md := make(map[string]string, len(msg.Metadata)+2)
+		for k, v := range msg.Metadata {
+			md[k] = v
+		}
+		cfg.Propagator.Inject(ctx, propagation.MapCarrier(md))
+		msg.Metadata = md
+
+		errCh := handler(ctx, msg)
+		if errCh == nil {
+			span.End()
+			return nil
+		}
+
+		outCh := make(chan error, 1)
+		go func() {
+			defer close(outCh)
+			defer span.End()
+
+			err := <-errCh
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				outCh <- err
+				return
+			}
+			if msg.ID != "" {
+				span.SetAttributes(attribute.String("messaging.message.id", msg.ID))
+			}
+		}()
+
+		return outCh
+	}
+}
+
+// SubscribeInterceptor returns a pubee.SubscribeInterceptor that extracts trace context from message metadata
+// and starts a consumer span for handling each message.
+func SubscribeInterceptor(opts ...Option) pubee.SubscribeInterceptor {
+	cfg := newConfig(opts)
+	tracer := cfg.TracerProvider.Tracer(tracerName)
+
+	return func(ctx context.Context, msg *pubee.ReceivedMessage, handler pubee.Handler) error {
+		if msg.Metadata != nil {
+			ctx = cfg.Propagator.Extract(ctx, propagation.MapCarrier(msg.Metadata))
+		}
+
+		ctx, span := tracer.Start(ctx, "process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attrSystem,
+				attribute.String("messaging.operation", "process"),
+				attribute.String("messaging.message.id", msg.ID),
+				attribute.Int("messaging.message.body.size", len(msg.Data)),
+			),
+		)
+		defer span.End()
+
+		err := handler(ctx, msg)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	}
+}
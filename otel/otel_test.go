@@ -0,0 +1,105 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+	pubeeotel "github.com/izumin5210/pubee/otel"
+)
+
+func TestInterceptors(t *testing.T) {
+	ctx := context.Background()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver,
+		pubee.WithPublishInterceptors(pubeeotel.PublishInterceptor(pubeeotel.WithTracerProvider(tp))),
+	)
+	subscriber := pubee.NewSubscriber(driver.NewSubscriberDriver(memory.DefaultTopic),
+		pubee.WithSubscribeInterceptors(pubeeotel.SubscribeInterceptor(pubeeotel.WithTracerProvider(tp))),
+	)
+
+	handledCh := make(chan trace.SpanContext, 1)
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		handledCh <- trace.SpanContextFromContext(ctx)
+		return nil
+	})
+	go subscriber.Subscribe(ctx)
+	defer subscriber.Close(ctx)
+
+	_, err := publisher.Publish(ctx, "foobarbaz").Get(ctx)
+	if err != nil {
+		t.Fatalf("failed to publish a message: %v", err)
+	}
+
+	var consumerSC trace.SpanContext
+	select {
+	case consumerSC = <-handledCh:
+	case <-time.After(time.Second):
+		t.Fatal("message was not handled")
+	}
+	publisher.Close(ctx)
+
+	if _, ok := driver.Messages()[0].Metadata["traceparent"]; !ok {
+		t.Error("Published message should have traceparent metadata")
+	}
+
+	spans := exporter.GetSpans()
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("Recorded spans are %d, want %d", got, want)
+	}
+
+	var producer tracetest.SpanStub
+	for _, s := range spans {
+		if s.SpanKind == trace.SpanKindProducer {
+			producer = s
+		}
+	}
+	if got, want := producer.SpanKind, trace.SpanKindProducer; got != want {
+		t.Fatalf("Producer span was not recorded")
+	}
+	if got, want := consumerSC.TraceID(), producer.SpanContext.TraceID(); got != want {
+		t.Errorf("Consumer span has trace ID %v, want %v", got, want)
+	}
+}
+
+func TestPublishInterceptor_WhenFailed(t *testing.T) {
+	ctx := context.Background()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	driver := memory.NewDriver()
+	driver.InjectFailure(func(*pubee.Message) error {
+		return errors.New("unfortunate error")
+	})
+	publisher := pubee.New(driver,
+		pubee.WithPublishInterceptors(pubeeotel.PublishInterceptor(pubeeotel.WithTracerProvider(tp))),
+	)
+
+	_, err := publisher.Publish(ctx, "foobarbaz").Get(ctx)
+	if err == nil {
+		t.Error("PublishResult.Get() should return an error")
+	}
+	publisher.Close(ctx)
+
+	spans := exporter.GetSpans()
+	if got, want := len(spans), 1; got != want {
+		t.Fatalf("Recorded spans are %d, want %d", got, want)
+	}
+	if got, want := spans[0].Status.Code, codes.Error; got != want {
+		t.Errorf("Span has status %v, want %v", got, want)
+	}
+	if got, want := len(spans[0].Events), 1; got != want {
+		t.Errorf("Span has %d events, want %d", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package pubee
+
+import "context"
+
+// PublishResult holds the result from a call to Publish.
+type PublishResult struct {
+	ready chan struct{}
+	id    string
+	err   error
+}
+
+func newPublishResult() *PublishResult {
+	return &PublishResult{ready: make(chan struct{})}
+}
+
+// Ready returns a channel that is closed when the result is ready.
+func (r *PublishResult) Ready() <-chan struct{} { return r.ready }
+
+// Get returns the server-generated message ID and/or error result of a Publish call.
+// Get blocks until the Publish call completes or the context is done.
+func (r *PublishResult) Get(ctx context.Context) (id string, err error) {
+	select {
+	case <-r.ready:
+		return r.id, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (r *PublishResult) set(id string, err error) {
+	r.id = id
+	r.err = err
+	close(r.ready)
+}
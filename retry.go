@@ -0,0 +1,188 @@
+package pubee
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// RetryConfig represents a retry policy for failed publishes.
+// Zero fields are filled with default values.
+//
+// Engine.Close waits for the last attempts of messages before flushing the driver, since drivers may be stopped by Flush.
+// So drivers should report results without Flush, e.g. with their batch timeouts.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of publish attempts per message, including the first one. Defaults to 3.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper bound of the wait between retries. Defaults to 10s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the backoff is multiplied by after each retry. Defaults to 2.
+	Multiplier float64
+	// Jitter is the randomization factor (0 to 1) applied to each backoff. Defaults to 0.2.
+	Jitter float64
+	// Deadline is the time limit for publishing a message including all retries. No limit when zero.
+	// Attempts are published with a context that is canceled at the deadline.
+	Deadline time.Duration
+	// IsRetryable reports whether an error should be retried.
+	// When nil, the Driver's RetryClassifier implementation or IsRetryable is used.
+	IsRetryable func(error) bool
+}
+
+func (c *RetryConfig) setDefaults() {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 10 * time.Second
+	}
+	if c.Multiplier < 1 {
+		c.Multiplier = 2
+	}
+	if c.Jitter <= 0 || c.Jitter > 1 {
+		c.Jitter = 0.2
+	}
+}
+
+func (c *RetryConfig) delay(backoff time.Duration) time.Duration {
+	return time.Duration(float64(backoff) * (1 - c.Jitter*rand.Float64()))
+}
+
+// RetryClassifier is implemented by drivers that know which of their errors are retryable.
+type RetryClassifier interface {
+	IsRetryable(error) bool
+}
+
+// IsRetryable reports whether err is a temporary error.
+// Errors implementing `Retryable() bool` or `Temporary() bool` decide by themselves, and the others are treated as permanent.
+func IsRetryable(err error) bool {
+	switch err {
+	case nil, context.Canceled, context.DeadlineExceeded:
+		return false
+	}
+	if e, ok := err.(interface{ Retryable() bool }); ok {
+		return e.Retryable()
+	}
+	if e, ok := err.(interface{ Temporary() bool }); ok {
+		return e.Temporary()
+	}
+	return false
+}
+
+// Retryable wraps err so that IsRetryable reports it as a retryable error.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string   { return e.err.Error() }
+func (e *retryableError) Retryable() bool { return true }
+func (e *retryableError) Cause() error    { return e.err }
+
+// retryingPublish returns a PublishHandler that publishes messages with the driver, and re-publishes them while errors are retryable.
+// It is the last handler of the interceptor chain, so that interceptors observe the final outcome.
+// The number of attempts is stored into attempts atomically before the returned channel is closed.
+func (p *engineImpl) retryingPublish(attempts *int32) PublishHandler {
+	return func(ctx context.Context, msg *Message) <-chan error {
+		cfg := p.cfg.Retry
+		if cfg == nil {
+			atomic.StoreInt32(attempts, 1)
+			return p.driver.Publish(ctx, msg)
+		}
+
+		p.retries.Add(1)
+		start := time.Now()
+		cancel := func() {}
+		if cfg.Deadline > 0 {
+			ctx, cancel = context.WithTimeout(ctx, cfg.Deadline)
+		}
+		errCh := p.driver.Publish(ctx, msg)
+
+		retryCh := make(chan error, 1)
+		go func() {
+			defer close(retryCh)
+			defer p.retries.Done()
+			defer cancel()
+
+			n, err := p.retry(ctx, msg, p.wait(ctx, errCh), start)
+			atomic.StoreInt32(attempts, int32(n))
+			if err != nil {
+				retryCh <- err
+			}
+		}()
+
+		return retryCh
+	}
+}
+
+// retry re-publishes the message while err is retryable, and returns the number of attempts and the last error.
+func (p *engineImpl) retry(ctx context.Context, msg *Message, err error, start time.Time) (int, error) {
+	cfg := p.cfg.Retry
+	if cfg == nil || err == nil {
+		return 1, err
+	}
+
+	isRetryable := cfg.IsRetryable
+	if isRetryable == nil {
+		if c, ok := p.driver.(RetryClassifier); ok {
+			isRetryable = c.IsRetryable
+		} else {
+			isRetryable = IsRetryable
+		}
+	}
+
+	backoff := cfg.InitialBackoff
+
+	attempt := 1
+	for ; err != nil && attempt < cfg.MaxAttempts && isRetryable(err); attempt++ {
+		delay := cfg.delay(backoff)
+		if cfg.Deadline > 0 && time.Since(start)+delay > cfg.Deadline {
+			break
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return attempt, err
+		}
+
+		GetErrorLog(ctx).Printf("retrying to publish message (attempt %d): %v (metadata: %v)", attempt+1, err, msg.Metadata)
+		err = p.wait(ctx, p.driver.Publish(ctx, msg))
+
+		backoff = time.Duration(float64(backoff) * cfg.Multiplier)
+		if backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+
+	return attempt, err
+}
+
+// wait returns the result of an attempt. It gives up on drivers ignoring ctx when the retry deadline is exceeded.
+func (p *engineImpl) wait(ctx context.Context, errCh <-chan error) error {
+	if p.cfg.Retry.Deadline <= 0 {
+		return <-errCh
+	}
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		if err := ctx.Err(); err == context.DeadlineExceeded {
+			return err
+		}
+		return <-errCh
+	}
+}
@@ -0,0 +1,180 @@
+package pubee
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/izumin5210/pubee/codec"
+	"github.com/izumin5210/pubee/unmarshal"
+)
+
+type Subscriber interface {
+	Handle(Handler)
+	Subscribe(context.Context) error
+	Close(context.Context) error
+}
+
+// Handler processes a received message.
+// The message is acked when all handlers return nil, and nacked otherwise.
+type Handler func(context.Context, *ReceivedMessage) error
+
+// Acker acknowledges a received message to the message broker.
+type Acker interface {
+	Ack()
+	Nack()
+}
+
+type ReceivedMessage struct {
+	ID          string
+	OrderingKey string
+	Data        []byte
+	Metadata    map[string]string
+	PublishTime time.Time
+	Acker       Acker
+
+	unmarshal unmarshal.Func
+	codecs    *codec.Registry
+	doneOnce  sync.Once
+}
+
+// Ack acknowledges the message. Only the first call of Ack or Nack takes effect.
+func (m *ReceivedMessage) Ack() {
+	m.doneOnce.Do(func() {
+		if m.Acker != nil {
+			m.Acker.Ack()
+		}
+	})
+}
+
+// Nack negatively acknowledges the message. Only the first call of Ack or Nack takes effect.
+func (m *ReceivedMessage) Nack() {
+	m.doneOnce.Do(func() {
+		if m.Acker != nil {
+			m.Acker.Nack()
+		}
+	})
+}
+
+// Unmarshal decodes the message data into out.
+// It uses the subscriber's unmarshal.Func if set, or a codec.Codec for the content type in the metadata.
+func (m *ReceivedMessage) Unmarshal(out interface{}) error {
+	if f := m.unmarshal; f != nil {
+		return f(m.Data, out)
+	}
+	if m.codecs != nil {
+		if c, ok := m.codecs.Lookup(m.Metadata[codec.ContentTypeKey]); ok {
+			return c.Unmarshal(m.Data, out)
+		}
+	}
+	return unmarshal.Default(m.Data, out)
+}
+
+func NewSubscriber(d SubscriberDriver, opts ...SubscriberOption) Subscriber {
+	cfg := new(SubscriberConfig)
+	cfg.ErrorLog = defaultErrorLog
+	cfg.Codecs = codec.DefaultRegistry
+	cfg.apply(opts)
+	return &subscriberImpl{
+		driver: d,
+		cfg:    cfg,
+		doneCh: make(chan struct{}),
+	}
+}
+
+type subscriberImpl struct {
+	driver    SubscriberDriver
+	cfg       *SubscriberConfig
+	wg        sync.WaitGroup
+	doneCh    chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func (s *subscriberImpl) Handle(h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context) error {
+	if l := s.cfg.ErrorLog; l != nil {
+		ctx = setErrorLog(ctx, l)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	go func() {
+		select {
+		case <-s.doneCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return s.driver.Receive(ctx, s.handle)
+}
+
+func (s *subscriberImpl) handle(ctx context.Context, msg *ReceivedMessage) {
+	if msg.unmarshal == nil {
+		msg.unmarshal = s.cfg.Unmarshal
+	}
+	if msg.codecs == nil {
+		msg.codecs = s.cfg.Codecs
+	}
+
+	var err error
+	if f := s.cfg.Interceptor; f == nil {
+		err = s.invoke(ctx, msg)
+	} else {
+		err = f(ctx, msg, s.invoke)
+	}
+
+	if err != nil {
+		GetErrorLog(ctx).Printf("failed to handle message: %v (id: %s, metadata: %v)", err, msg.ID, msg.Metadata)
+		if f := s.cfg.OnFailHandleFunc; f != nil {
+			f(msg, err)
+		}
+		msg.Nack()
+		return
+	}
+
+	msg.Ack()
+}
+
+func (s *subscriberImpl) invoke(ctx context.Context, msg *ReceivedMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	s.mu.RLock()
+	handlers := s.handlers
+	s.mu.RUnlock()
+
+	for _, h := range handlers {
+		if err := h(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *subscriberImpl) Close(ctx context.Context) error {
+	if l := s.cfg.ErrorLog; l != nil {
+		ctx = setErrorLog(ctx, l)
+	}
+
+	s.closeOnce.Do(func() { close(s.doneCh) })
+	s.wg.Wait()
+	return s.driver.Close(ctx)
+}
@@ -0,0 +1,76 @@
+package pubee
+
+import (
+	"github.com/izumin5210/pubee/codec"
+	"github.com/izumin5210/pubee/unmarshal"
+)
+
+type SubscriberConfig struct {
+	ErrorLog         Logger
+	Unmarshal        unmarshal.Func
+	Codecs           *codec.Registry
+	OnFailHandleFunc func(*ReceivedMessage, error)
+	Interceptor      SubscribeInterceptor
+}
+
+func (c *SubscriberConfig) apply(opts []SubscriberOption) {
+	for _, f := range opts {
+		f.applySubscriberOption(c)
+	}
+}
+
+type SubscriberOption interface {
+	applySubscriberOption(*SubscriberConfig)
+}
+
+type SubscriberOptionFunc func(*SubscriberConfig)
+
+func (o SubscriberOptionFunc) applySubscriberOption(c *SubscriberConfig) { o(c) }
+
+var (
+	_ SubscriberOption = (SubscriberOptionFunc)(nil)
+)
+
+func WithSubscriberErrorLog(l Logger) SubscriberOption {
+	return SubscriberOptionFunc(func(c *SubscriberConfig) {
+		c.ErrorLog = l
+	})
+}
+
+func WithUnmarshalJSON() SubscriberOption {
+	return WithUnmarshalFunc(unmarshal.JSON)
+}
+
+func WithUnmarshalProtobuf() SubscriberOption {
+	return WithUnmarshalFunc(unmarshal.Protobuf)
+}
+
+// WithCodecRegistry returns a SubscriberOption that set a codec.Registry to look up a codec.Codec by the content type of received messages.
+// codec.DefaultRegistry is used by default.
+func WithCodecRegistry(r *codec.Registry) SubscriberOption {
+	return SubscriberOptionFunc(func(c *SubscriberConfig) { c.Codecs = r })
+}
+
+// WithUnmarshalFunc returns a SubscriberOption that unmarshals all messages with the unmarshal.Func regardless of their content types.
+func WithUnmarshalFunc(f unmarshal.Func) SubscriberOption {
+	return SubscriberOptionFunc(func(c *SubscriberConfig) { c.Unmarshal = f })
+}
+
+func WithOnFailHandle(f func(*ReceivedMessage, error)) SubscriberOption {
+	return SubscriberOptionFunc(func(c *SubscriberConfig) {
+		c.OnFailHandleFunc = f
+	})
+}
+
+func WithSubscribeInterceptors(interceptors ...SubscribeInterceptor) SubscriberOption {
+	return SubscriberOptionFunc(func(c *SubscriberConfig) {
+		if len(interceptors) == 0 {
+			return
+		}
+		chain := interceptors
+		if f := c.Interceptor; f != nil {
+			chain = append([]SubscribeInterceptor{f}, interceptors...)
+		}
+		c.Interceptor = chainSubscribeInterceptors(chain)
+	})
+}
@@ -0,0 +1,219 @@
+package pubee_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/izumin5210/pubee"
+	"github.com/izumin5210/pubee/drivers/memory"
+)
+
+type fakeAcker struct {
+	acked, nacked int
+}
+
+func (a *fakeAcker) Ack()  { a.acked++ }
+func (a *fakeAcker) Nack() { a.nacked++ }
+
+type fakeSubscriberDriver struct {
+	Messages []*pubee.ReceivedMessage
+	closed   bool
+}
+
+var _ pubee.SubscriberDriver = (*fakeSubscriberDriver)(nil)
+
+func (d *fakeSubscriberDriver) Receive(ctx context.Context, f func(context.Context, *pubee.ReceivedMessage)) error {
+	for _, msg := range d.Messages {
+		f(ctx, msg)
+	}
+	return nil
+}
+
+func (d *fakeSubscriberDriver) Close(context.Context) error {
+	d.closed = true
+	return nil
+}
+
+func TestSubscriber(t *testing.T) {
+	type Book struct {
+		Title string `json:"title"`
+	}
+
+	acker := new(fakeAcker)
+	driver := &fakeSubscriberDriver{
+		Messages: []*pubee.ReceivedMessage{
+			{ID: "1", Data: []byte(`{"title":"The Go Programming Language"}`), Acker: acker},
+		},
+	}
+	subscriber := pubee.NewSubscriber(driver,
+		pubee.WithUnmarshalJSON(),
+		pubee.WithOnFailHandle(func(msg *pubee.ReceivedMessage, err error) {
+			t.Errorf("Handle() returns %v, want nil", err)
+		}),
+	)
+
+	var books []Book
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		var book Book
+		err := msg.Unmarshal(&book)
+		if err != nil {
+			return err
+		}
+		books = append(books, book)
+		return nil
+	})
+
+	err := subscriber.Subscribe(context.Background())
+	if err != nil {
+		t.Errorf("Subscribe() returned %v, want nil", err)
+	}
+	subscriber.Close(context.Background())
+
+	if got, want := len(books), 1; got != want {
+		t.Errorf("Received messages are %d, want %d", got, want)
+	} else if got, want := books[0].Title, "The Go Programming Language"; got != want {
+		t.Errorf("Received message has title %q, want %q", got, want)
+	}
+	if got, want := acker.acked, 1; got != want {
+		t.Errorf("Ack is called %d times, want %d", got, want)
+	}
+	if got, want := acker.nacked, 0; got != want {
+		t.Errorf("Nack is called %d times, want %d", got, want)
+	}
+	if !driver.closed {
+		t.Error("SubscriberDriver should be closed")
+	}
+}
+
+func TestSubscriber_WhenHandlerFailed(t *testing.T) {
+	acker := new(fakeAcker)
+	driver := &fakeSubscriberDriver{
+		Messages: []*pubee.ReceivedMessage{
+			{ID: "1", Data: []byte("foobarbaz"), Acker: acker},
+			{ID: "2", Data: []byte("panic"), Acker: acker},
+		},
+	}
+	var calledCnt int
+	logger := new(fakeLogger)
+	subscriber := pubee.NewSubscriber(driver,
+		pubee.WithOnFailHandle(func(msg *pubee.ReceivedMessage, err error) {
+			calledCnt++
+		}),
+		pubee.WithSubscriberErrorLog(logger),
+	)
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		var s string
+		msg.Unmarshal(&s)
+		if s == "panic" {
+			panic("unexpected")
+		}
+		return errors.New("unfortunate error")
+	})
+
+	subscriber.Subscribe(context.Background())
+	subscriber.Close(context.Background())
+
+	if got, want := calledCnt, 2; got != want {
+		t.Errorf("OnFailHandle is called %d times, want %d", got, want)
+	}
+	if got, want := len(logger.logs), 2; got != want {
+		t.Errorf("ErrorLog prints %d items, want %d", got, want)
+	}
+	if got, want := acker.nacked, 2; got != want {
+		t.Errorf("Nack is called %d times, want %d", got, want)
+	}
+	if got, want := acker.acked, 0; got != want {
+		t.Errorf("Ack is called %d times, want %d", got, want)
+	}
+}
+
+func TestSubscriber_WithSubscribeInterceptors(t *testing.T) {
+	var ops []string
+	driver := &fakeSubscriberDriver{
+		Messages: []*pubee.ReceivedMessage{
+			{ID: "1", Data: []byte("foobarbaz"), Acker: new(fakeAcker)},
+		},
+	}
+	subscriber := pubee.NewSubscriber(driver,
+		pubee.WithSubscribeInterceptors(
+			func(ctx context.Context, msg *pubee.ReceivedMessage, handle pubee.Handler) error {
+				ops = append(ops, "1-before")
+				err := handle(ctx, msg)
+				ops = append(ops, "1-after")
+				return err
+			},
+			func(ctx context.Context, msg *pubee.ReceivedMessage, handle pubee.Handler) error {
+				ops = append(ops, "2-before")
+				err := handle(ctx, msg)
+				ops = append(ops, "2-after")
+				return err
+			},
+		),
+	)
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		ops = append(ops, "handle")
+		return nil
+	})
+
+	subscriber.Subscribe(context.Background())
+	subscriber.Close(context.Background())
+
+	if got, want := ops, []string{
+		"1-before", "2-before", "handle", "2-after", "1-after",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("interceptors called order is %v, want %v", got, want)
+	}
+}
+
+func TestSubscriber_UnmarshalByContentType(t *testing.T) {
+	driver := memory.NewDriver()
+	publisher := pubee.New(driver)
+	subscriber := pubee.NewSubscriber(driver.NewSubscriberDriver(memory.DefaultTopic))
+
+	type Book struct {
+		Title string `json:"title"`
+	}
+
+	doneCh := make(chan struct{})
+	var (
+		books  []Book
+		errors []error
+	)
+	subscriber.Handle(func(ctx context.Context, msg *pubee.ReceivedMessage) error {
+		var book Book
+		err := msg.Unmarshal(&book)
+		books = append(books, book)
+		errors = append(errors, err)
+		if len(books) == 2 {
+			close(doneCh)
+		}
+		return nil
+	})
+	go subscriber.Subscribe(context.Background())
+	defer subscriber.Close(context.Background())
+
+	publisher.Publish(context.Background(), &Book{Title: "The Go Programming Language"})
+	publisher.Publish(context.Background(), &wrappers.StringValue{Value: "Proto"}, pubee.WithProtobuf())
+	publisher.Close(context.Background())
+
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("messages were not received")
+	}
+
+	if got, want := books[0].Title, "The Go Programming Language"; got != want {
+		t.Errorf("Received message has title %q, want %q", got, want)
+	}
+	if errors[0] != nil {
+		t.Errorf("Unmarshal() returned %v, want nil", errors[0])
+	}
+	if errors[1] == nil {
+		t.Error("Unmarshal() should return an error for protobuf messages into non-proto values")
+	}
+}
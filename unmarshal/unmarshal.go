@@ -0,0 +1,34 @@
+package unmarshal
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type Func func([]byte, interface{}) error
+
+func Default(data []byte, out interface{}) error {
+	switch v := out.(type) {
+	case *string:
+		*v = string(data)
+		return nil
+	case *[]byte:
+		*v = data
+		return nil
+	default:
+		return JSON(data, out)
+	}
+}
+
+func JSON(data []byte, out interface{}) error {
+	return json.Unmarshal(data, out)
+}
+
+func Protobuf(data []byte, out interface{}) error {
+	if m, ok := out.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	return errors.New("message should implement proto.Message interface")
+}